@@ -0,0 +1,163 @@
+package workers
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// The snapshot passed to AutoScaleFunc's decide function
+type PoolStats = Stats
+
+// The thresholds and bounds used by EnableAutoscale
+type AutoscaleConfig struct {
+	// How often to check the pool's usage
+	Interval time.Duration
+
+	// Scale up when at least this fraction of the workers are busy (or
+	// jobs are queued), and down when at most this fraction are busy.
+	// UpThreshold must be greater than DownThreshold
+	UpThreshold   float64
+	DownThreshold float64
+
+	// The fraction of the current size to add or remove when scaling,
+	// always at least one worker
+	UpStep   float64
+	DownStep float64
+
+	// The bounds of the pool's size. A Max of zero means no upper bound
+	Min int
+	Max int
+}
+
+// Scale the WorkerPool automatically based on the fraction of its
+// workers that are busy, until the returned stop function is called or
+// the pool is stopped
+//
+// Steps are limited so that scaling up never leaves the pool under the
+// down threshold and scaling down never leaves it over the up threshold,
+// which keeps the pool from bouncing between two sizes.
+//
+// Panics when UpThreshold <= DownThreshold or Max < Min
+func (w *WorkerPool) EnableAutoscale(cfg AutoscaleConfig) (stop func()) {
+	if cfg.UpThreshold <= cfg.DownThreshold {
+		panic("the up threshold must be greater than the down threshold")
+	}
+	if cfg.Max != 0 && cfg.Max < cfg.Min {
+		panic("max must not be less than min")
+	}
+	return w.AutoScaleFunc(cfg.Interval, cfg.decide)
+}
+
+func (cfg AutoscaleConfig) decide(stats PoolStats) int {
+	size, busy := stats.Size, float64(stats.Busy)
+	target := size
+
+	usage := 1.0
+	if size > 0 && stats.QueueLen == 0 {
+		usage = busy / float64(size)
+	}
+	if usage >= cfg.UpThreshold {
+		target = size + cfg.step(size, cfg.UpStep)
+		// don't add so many workers that the pool is under-used
+		for target > size+1 && busy <= float64(target)*cfg.DownThreshold {
+			target--
+		}
+	} else if usage <= cfg.DownThreshold {
+		target = size - cfg.step(size, cfg.DownStep)
+		// don't remove so many workers that the pool is over-used
+		for target < size && busy >= float64(target)*cfg.UpThreshold {
+			target++
+		}
+	}
+
+	if target < cfg.Min {
+		target = cfg.Min
+	}
+	if cfg.Max != 0 && target > cfg.Max {
+		target = cfg.Max
+	}
+	return target
+}
+
+func (cfg AutoscaleConfig) step(size int, fraction float64) int {
+	step := int(math.Ceil(float64(size) * fraction))
+	if step < 1 {
+		return 1
+	}
+	return step
+}
+
+// Scale the WorkerPool toward the size returned by decide once per
+// interval, until the returned stop function is called or the pool is
+// stopped
+//
+// The decide function receives the current worker counts and returns
+// the desired size, which is clamped to zero. The controller only
+// handles the mechanics of scaling; the policy is entirely up to decide.
+// A restarted pool is not scaled until AutoScaleFunc is called again.
+func (w *WorkerPool) AutoScaleFunc(interval time.Duration, decide func(PoolStats) int) (stop func()) {
+	done := make(chan struct{})
+	ctx := w.Context()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if ctx.Err() != nil {
+					// stopped at the same moment as the tick
+					return
+				}
+				target := decide(w.Stats())
+				if target < 0 {
+					target = 0
+				}
+				// blocks until down-scaled workers have stopped,
+				// so the next decision sees a settled pool
+				_ = w.ScaleTo(target)
+			case <-done:
+				return
+			case <-ctx.Done():
+				// leave the size as it is for Restart
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// The number of scale events buffered for ScaleEvents before new
+// events are dropped
+const scaleEventBuffer = 64
+
+// A change in a WorkerPool's size, sent on ScaleEvents
+type ScaleEvent struct {
+	OldSize int
+	NewSize int
+	Time    time.Time
+}
+
+// Get a channel which receives an event after every ScaleUp or
+// ScaleDown (including through ScaleTo, Resize and the autoscalers)
+// completes, or ScaleDownIdle or WithIdleTimeout shrinks the pool
+//
+// Each worker retired by WithIdleTimeout is its own event. Events are
+// never waited for: when the channel's buffer is full, new events are
+// dropped, so a slow consumer cannot stall scaling.
+func (w *WorkerPool) ScaleEvents() <-chan ScaleEvent {
+	return w.scaleEvents
+}
+
+func (w *WorkerPool) emitScale(oldSize, newSize int) {
+	select {
+	case w.scaleEvents <- ScaleEvent{OldSize: oldSize, NewSize: newSize, Time: time.Now()}:
+	default:
+	}
+}
@@ -0,0 +1,135 @@
+package workers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_AutoScaleFunc(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+
+	stop := pool.AutoScaleFunc(time.Millisecond, func(stats PoolStats) int {
+		return stats.Size / 2
+	})
+	<-time.After(20 * time.Millisecond)
+	stop()
+	stop() // must be safe to call twice
+
+	if pool.Size() != 0 {
+		t.Error("pool size should be 0, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_AutoScaleFunc_Stop(t *testing.T) {
+	pool := NewPool(2, func(...interface{}) {})
+	stop := pool.AutoScaleFunc(time.Millisecond, func(stats PoolStats) int {
+		return stats.Size + 1
+	})
+	defer stop()
+
+	pool.Stop()
+	<-time.After(5 * time.Millisecond) // let a tick in progress finish
+	size := pool.Size()
+	<-time.After(20 * time.Millisecond)
+	if pool.Size() != size {
+		t.Error("a stopped pool should not be scaled, but its size went from", size, "to", pool.Size())
+	}
+}
+
+func TestAutoscaleConfig_decide(t *testing.T) {
+	cfg := AutoscaleConfig{
+		UpThreshold:   0.9,
+		DownThreshold: 0.5,
+		UpStep:        0.1,
+		DownStep:      0.25,
+		Min:           2,
+		Max:           110,
+	}
+
+	tests := []struct {
+		stats    PoolStats
+		expected int
+	}{
+		{PoolStats{Size: 100, Busy: 95}, 110},
+		{PoolStats{Size: 105, Busy: 105}, 110},        // capped at Max
+		{PoolStats{Size: 100, Busy: 70}, 100},         // between thresholds
+		{PoolStats{Size: 100, Busy: 10}, 75},          // full down step
+		{PoolStats{Size: 100, Busy: 45}, 75},          // 45/75 stays under 0.9
+		{PoolStats{Size: 100, Busy: 50}, 75},          // 50/75 stays under 0.9
+		{PoolStats{Size: 4, Busy: 0}, 3},              // at least one worker
+		{PoolStats{Size: 2, Busy: 0}, 2},              // capped at Min
+		{PoolStats{Size: 0, Busy: 0, QueueLen: 1}, 2}, // queued jobs need workers
+	}
+	for _, test := range tests {
+		if target := cfg.decide(test.stats); target != test.expected {
+			t.Error("target for", test.stats, "should be", test.expected, "not", target)
+		}
+	}
+}
+
+func TestWorkerPool_EnableAutoscale(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+	stop := pool.EnableAutoscale(AutoscaleConfig{
+		Interval:      time.Millisecond,
+		UpThreshold:   0.9,
+		DownThreshold: 0.5,
+		UpStep:        0.1,
+		DownStep:      0.5,
+		Min:           3,
+	})
+	defer stop()
+
+	deadline := time.After(time.Second)
+	for pool.Size() != 3 {
+		select {
+		case <-deadline:
+			t.Fatal("idle pool should shrink to 3, not", pool.Size())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestWorkerPool_ScaleEvents(t *testing.T) {
+	pool := NewPool(2, func(...interface{}) {})
+	defer pool.Stop()
+
+	_ = pool.ScaleUp(5)
+	_ = pool.ScaleDown(3)
+	for _, expected := range []ScaleEvent{{OldSize: 2, NewSize: 5}, {OldSize: 5, NewSize: 3}} {
+		event := <-pool.ScaleEvents()
+		if event.OldSize != expected.OldSize || event.NewSize != expected.NewSize {
+			t.Error("scale event should go from", expected.OldSize, "to", expected.NewSize, "not", event.OldSize, "to", event.NewSize)
+		}
+		if event.Time.IsZero() {
+			t.Error("scale event should have a time")
+		}
+	}
+
+	// a full buffer must not block scaling
+	for i := 0; i < scaleEventBuffer+1; i++ {
+		_ = pool.ScaleTo(3 + i%2)
+	}
+}
+
+func TestWorkerPool_ScaleEvents_Idle(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(4))
+	defer pool.Stop()
+	<-time.After(time.Millisecond) // wait for the workers to be idle
+
+	size, _ := pool.ScaleDownIdle(1)
+	event := <-pool.ScaleEvents()
+	if event.OldSize != 4 || event.NewSize != size {
+		t.Error("scale event should go from 4 to", size, "not", event.OldSize, "to", event.NewSize)
+	}
+
+	pool = New(func(...interface{}) {}, WithSize(2), WithIdleTimeout(time.Millisecond), WithMinWorkers(1))
+	defer pool.Stop()
+	select {
+	case event = <-pool.ScaleEvents():
+		if event.OldSize != 2 || event.NewSize != 1 {
+			t.Error("scale event should go from 2 to 1, not", event.OldSize, "to", event.NewSize)
+		}
+	case <-time.After(time.Second):
+		t.Error("retiring an idle worker should emit a scale event")
+	}
+}
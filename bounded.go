@@ -0,0 +1,36 @@
+package workers
+
+import "errors"
+
+// Returned by BoundedPool#Run when the job buffer is full
+var ErrQueueFull = errors.New("the job queue is full")
+
+// A WorkerPool which rejects jobs when its job buffer is full, instead
+// of blocking until there is room
+//
+// Every WorkerPool method is available; Run returns ErrQueueFull rather
+// than blocking.
+type BoundedPool struct {
+	*WorkerPool
+}
+
+// Create a new BoundedPool with an initial worker count and a job buffer
+// which holds at most maxQueue jobs waiting for a worker
+//
+// Panics when size < 0
+func NewBoundedPool(size, maxQueue int, run RunFunc) *BoundedPool {
+	return &BoundedPool{
+		WorkerPool: NewBufferedPool(size, maxQueue, run),
+	}
+}
+
+// Add a job to this BoundedPool without blocking
+//
+// Returns ErrQueueFull if every worker is busy and the job buffer is
+// full, so overloaded services can shed load instead of queueing it.
+func (b *BoundedPool) Run(data ...interface{}) error {
+	if !b.TrySubmit(data...) {
+		return ErrQueueFull
+	}
+	return nil
+}
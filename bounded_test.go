@@ -0,0 +1,27 @@
+package workers
+
+import "testing"
+
+func TestNewBoundedPool(t *testing.T) {
+	pool := NewBoundedPool(1, 2, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	defer pool.Stop()
+
+	err := pool.Run(1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	waitBusy(t, pool.WorkerPool, 1)
+
+	for i := 0; i < 2; i++ {
+		err = pool.Run(i)
+		if err != nil {
+			t.Error("Error should be nil, not", err.Error())
+		}
+	}
+	err = pool.Run(3)
+	if err != ErrQueueFull {
+		t.Error("Error should be", ErrQueueFull, "not", err)
+	}
+}
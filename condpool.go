@@ -0,0 +1,237 @@
+package workers
+
+import (
+	"errors"
+	"sync"
+)
+
+// The workers of a pool which keeps its jobs in its own queue behind a
+// mutex, rather than a channel, so that the queue decides the order
+// jobs are run in
+type condPool struct {
+	// The worker's run function
+	run RunFunc
+
+	// The queued jobs
+	queue jobQueue
+
+	// The size of this pool, the number of busy workers, and the number
+	// of workers which should stop when they next look for a job
+	size     int
+	busy     int
+	stopping int
+	stopped  bool
+
+	// The number of jobs which panicked, and the function called when
+	// one does
+	panicked uint64
+	onPanic  func(recovered interface{}, job []interface{})
+
+	// Guards every field above and the queue, and signals workers
+	// waiting for jobs
+	mutex sync.Mutex
+	cond  *sync.Cond
+}
+
+// The queue of a condPool, whose methods are called with its mutex held
+type jobQueue interface {
+	// Get the number of queued jobs
+	Len() int
+
+	// Remove the next job to run, or return false if there is none
+	take() ([]interface{}, bool)
+
+	// Remove every queued job
+	reset()
+}
+
+// Create a new condPool with an initial worker count, which takes jobs
+// from queue
+//
+// Panics when size < 0
+func newCondPool(size int, run RunFunc, queue jobQueue) *condPool {
+	if size < 0 {
+		panic("size must be greater than zero")
+	}
+	pool := &condPool{
+		run:   run,
+		queue: queue,
+		size:  size,
+	}
+	pool.cond = sync.NewCond(&pool.mutex)
+	// spawn workers up to the limit
+	pool.createWorkers(size)
+	return pool
+}
+
+// Queue a job by calling add with the mutex held, unless the pool has
+// been stopped, and wake a worker to run it
+func (p *condPool) push(add func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stopped {
+		return
+	}
+	add()
+	p.cond.Signal()
+}
+
+// Resize the pool by scaling up or down to accommodate a new size
+func (p *condPool) ScaleTo(newSize int) error {
+	size := p.Size()
+	if newSize < size {
+		return p.ScaleDown(newSize)
+	}
+	if newSize > size {
+		return p.ScaleUp(newSize)
+	}
+	return errors.New("newSize must not be equal to the current size")
+}
+
+// Scale the pool up to a new specified size
+func (p *condPool) ScaleUp(newSize int) error {
+	p.mutex.Lock()
+	if newSize <= p.size {
+		p.mutex.Unlock()
+		return errors.New("the new size must be greater than the current size")
+	}
+	delta := newSize - p.size
+	p.size = newSize
+	p.mutex.Unlock()
+
+	p.createWorkers(delta)
+	return nil
+}
+
+// Scale the pool down to a new specified size
+//
+// Does not block; busy workers stop after finishing their current job.
+func (p *condPool) ScaleDown(newSize int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if newSize < 0 || newSize >= p.size {
+		return errors.New("the new size must be between zero and the current size")
+	}
+	p.stopping += p.size - newSize
+	p.size = newSize
+	p.cond.Broadcast()
+	return nil
+}
+
+// Stop the pool, discarding any queued jobs
+//
+// Workers stop after finishing their current job.
+func (p *condPool) Stop() {
+	p.mutex.Lock()
+	p.stopped = true
+	p.queue.reset()
+	p.mutex.Unlock()
+	p.cond.Broadcast()
+}
+
+// Set the function called when the run function panics
+//
+// Panics are always recovered so the worker keeps running; the
+// handler receives the recovered value and the job's arguments.
+func (p *condPool) OnPanic(handler func(recovered interface{}, job []interface{})) {
+	p.mutex.Lock()
+	p.onPanic = handler
+	p.mutex.Unlock()
+}
+
+// Get the number of jobs which panicked
+func (p *condPool) Panicked() uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.panicked
+}
+
+// Get the total number of workers in this pool
+func (p *condPool) Size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.size
+}
+
+// Get the number of busy workers in this pool
+func (p *condPool) Busy() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.busy
+}
+
+// Get the number of workers currently waiting for jobs
+func (p *condPool) Waiting() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.size - p.busy
+}
+
+// Get the number of workers waiting to close in this pool
+func (p *condPool) Excess() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stopping
+}
+
+// Get the number of jobs waiting in the queue
+func (p *condPool) QueueLen() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.queue.Len()
+}
+
+func (p *condPool) createWorkers(count int) {
+	for i := 0; i < count; i++ {
+		go func() {
+			for {
+				job, ok := p.next()
+				if !ok {
+					return
+				}
+				p.runJob(job)
+			}
+		}()
+	}
+}
+
+// Wait for the next job, or return false if this worker should stop
+func (p *condPool) next() ([]interface{}, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for {
+		for p.queue.Len() == 0 && p.stopping == 0 && !p.stopped {
+			p.cond.Wait()
+		}
+		if p.stopped {
+			return nil, false
+		}
+		if p.stopping > 0 {
+			p.stopping--
+			return nil, false
+		}
+		// the queue may drop jobs instead of returning them
+		if job, ok := p.queue.take(); ok {
+			p.busy++
+			return job, true
+		}
+	}
+}
+
+func (p *condPool) runJob(job []interface{}) {
+	defer func() {
+		// keep the worker alive if the job panics
+		recovered := recover()
+		p.mutex.Lock()
+		p.busy--
+		onPanic := p.onPanic
+		if recovered != nil {
+			p.panicked++
+		}
+		p.mutex.Unlock()
+		if recovered != nil && onPanic != nil {
+			onPanic(recovered, job)
+		}
+	}()
+	p.run(job...)
+}
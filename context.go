@@ -0,0 +1,39 @@
+package workers
+
+import "context"
+
+type RunFuncCtx func(context.Context, ...interface{})
+
+// Create a new WorkerPool with an initial worker count whose run
+// function receives the context each job was submitted with
+//
+// Panics when size < 0
+func NewPoolCtx(size int, run RunFuncCtx) *WorkerPool {
+	pool := newPool(ignoreError(run), options{size: size})
+	pool.fixedRun = "NewPoolCtx"
+	return pool
+}
+
+// Add a job to this WorkerPool along with a context
+//
+// Returns the context's error if it is cancelled before a worker accepts
+// the job. If it is cancelled after the job is queued but before a worker
+// starts it, the job is skipped. Pools created with NewPoolCtx pass the
+// context to their run function.
+func (w *WorkerPool) RunCtx(ctx context.Context, data ...interface{}) error {
+	return w.enqueueCtx(ctx, data)
+}
+
+func ignoreContext(run RunFunc) runFunc {
+	return func(_ context.Context, _ int, data ...interface{}) (interface{}, error) {
+		run(data...)
+		return nil, nil
+	}
+}
+
+func ignoreError(run RunFuncCtx) runFunc {
+	return func(ctx context.Context, _ int, data ...interface{}) (interface{}, error) {
+		run(ctx, data...)
+		return nil, nil
+	}
+}
@@ -0,0 +1,45 @@
+package workers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewPoolCtx(t *testing.T) {
+	type key struct{}
+	values := make(chan interface{}, 1)
+	pool := NewPoolCtx(1, func(ctx context.Context, data ...interface{}) {
+		values <- ctx.Value(key{})
+	})
+
+	err := pool.RunCtx(context.WithValue(context.Background(), key{}, "value"), 1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if value := <-values; value != "value" {
+		t.Error("run function should receive the job's context, got", value)
+	}
+}
+
+func TestWorkerPool_RunCtx(t *testing.T) {
+	ran := make(chan interface{}, 2)
+	pool := NewBufferedPool(0, 2, func(data ...interface{}) {
+		ran <- data[0]
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = pool.RunCtx(ctx, "cancelled")
+	_ = pool.RunCtx(context.Background(), "kept")
+	cancel()
+
+	_ = pool.ScaleUp(1)
+	if value := <-ran; value != "kept" {
+		t.Error("cancelled job should be skipped, but", value, "ran")
+	}
+
+	full := NewPool(0, func(...interface{}) {})
+	err := full.RunCtx(ctx, 1)
+	if err != context.Canceled {
+		t.Error("Error should be", context.Canceled, "not", err)
+	}
+}
@@ -0,0 +1,82 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// A WorkerPool which collapses identical jobs: while a job is queued or
+// running, other jobs with the same key are dropped
+//
+// Every WorkerPool method is available; jobs added with Run are
+// deduplicated, while jobs added with other methods are not tracked and
+// never free the key of a job added with Run.
+type DedupPool struct {
+	// The number of dropped jobs, updated atomically. Kept first so that
+	// it is 64-bit aligned on 32-bit platforms
+	deduped uint64
+
+	*WorkerPool
+
+	// The function which gives each job's key
+	key func(...interface{}) string
+
+	// The keys of queued and running jobs
+	inFlight      map[string]struct{}
+	inFlightMutex sync.Mutex
+}
+
+// Create a new DedupPool with an initial worker count, where key gives
+// the key that identical jobs share
+//
+// Panics when size < 0
+func NewDedupPool(size int, key func(...interface{}) string, run RunFunc) *DedupPool {
+	d := &DedupPool{
+		key:      key,
+		inFlight: make(map[string]struct{}),
+	}
+	d.WorkerPool = NewPool(size, run)
+	return d
+}
+
+// Add a job to this DedupPool, unless a job with the same key is
+// already queued or running
+//
+// Returns false if the job was dropped. The key is freed once the job
+// has finished, or if it is discarded without running, for example
+// when the pool is stopped.
+func (d *DedupPool) Run(data ...interface{}) bool {
+	k := d.key(data...)
+	d.inFlightMutex.Lock()
+	if _, ok := d.inFlight[k]; ok {
+		d.inFlightMutex.Unlock()
+		atomic.AddUint64(&d.deduped, 1)
+		return false
+	}
+	d.inFlight[k] = struct{}{}
+	d.inFlightMutex.Unlock()
+
+	job := d.newJob(context.Background(), data)
+	job.release = func() {
+		d.release(k)
+	}
+	if d.send(job, nil, true) != nil {
+		// the job has been discarded, freeing its key
+		_ = d.onClosed()
+		return false
+	}
+	return true
+}
+
+// Get the number of jobs which were dropped because a job with the
+// same key was already queued or running
+func (d *DedupPool) Deduped() uint64 {
+	return atomic.LoadUint64(&d.deduped)
+}
+
+func (d *DedupPool) release(key string) {
+	d.inFlightMutex.Lock()
+	delete(d.inFlight, key)
+	d.inFlightMutex.Unlock()
+}
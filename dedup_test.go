@@ -0,0 +1,93 @@
+package workers
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewDedupPool(t *testing.T) {
+	var ran int32
+	gate := make(chan bool)
+	pool := NewDedupPool(2, func(data ...interface{}) string {
+		return fmt.Sprint(data[0])
+	}, func(...interface{}) {
+		<-gate
+		atomic.AddInt32(&ran, 1)
+	})
+	defer pool.Stop()
+
+	if !pool.Run("a") || !pool.Run("b") {
+		t.Error("jobs with different keys should be accepted")
+	}
+	if pool.Run("a") {
+		t.Error("job with an in-flight key should be dropped")
+	}
+	close(gate)
+	pool.Wait()
+
+	if !pool.Run("a") {
+		t.Error("job should be accepted once the identical job has finished")
+	}
+	pool.Wait()
+	if atomic.LoadInt32(&ran) != 3 {
+		t.Error("jobs run should equal 3, not", atomic.LoadInt32(&ran))
+	}
+	if pool.Deduped() != 1 {
+		t.Error("deduplicated jobs should equal 1, not", pool.Deduped())
+	}
+}
+
+func TestDedupPool_Stop(t *testing.T) {
+	pool := NewDedupPool(1, func(data ...interface{}) string {
+		return fmt.Sprint(data[0])
+	}, func(...interface{}) {})
+	defer pool.Stop()
+	pool.Pause()
+	<-time.After(time.Millisecond) // wait for the worker to pause
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			_ = recover() // the pool is stopped before the job is taken
+		}()
+		pool.Run("k")
+	}()
+	<-time.After(time.Millisecond)
+	pool.Stop()
+	<-done
+
+	pool.Resume()
+	pool.Restart()
+	if !pool.Run("k") {
+		t.Error("the key of a discarded job should be freed")
+	}
+}
+
+func TestDedupPool_TrySubmit(t *testing.T) {
+	pool := NewDedupPool(2, func(data ...interface{}) string {
+		return fmt.Sprint(data[0])
+	}, func(data ...interface{}) {
+		<-data[1].(chan bool)
+	})
+	defer pool.Stop()
+	<-time.After(time.Millisecond) // wait for both workers to be ready
+
+	gate := make(chan bool)
+	defer close(gate)
+	pool.Run("k", gate)
+	waitBusy(t, pool.WorkerPool, 1)
+
+	done := make(chan bool)
+	close(done)
+	if !pool.TrySubmit("k", done) {
+		t.Fatal("the idle worker should accept the job")
+	}
+	<-time.After(time.Millisecond) // wait for the untracked job to finish
+
+	if pool.Run("k", gate) {
+		t.Error("an untracked job should not free the key of a running job")
+	}
+}
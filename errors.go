@@ -0,0 +1,69 @@
+package workers
+
+import "context"
+
+type ErrFunc func(...interface{}) error
+
+// What to do with a job's error when the Errors channel is full
+type DropPolicy int
+
+const (
+	// Discard the new error, keeping the errors already buffered
+	DropNewest DropPolicy = iota
+
+	// Discard the oldest buffered error to make room for the new one
+	DropOldest
+)
+
+// Create a new WorkerPool with an initial worker count whose run
+// function returns an error, which is sent on the Errors channel
+//
+// Workers never block on the Errors channel. When its buffer of
+// errBufSize errors is full, the policy decides which error is dropped.
+//
+// Options such as WithRetry may be passed to configure the pool further.
+//
+// Panics when size < 0
+func NewPoolErr(size, errBufSize int, policy DropPolicy, run ErrFunc, opts ...Option) *WorkerPool {
+	o := options{size: size}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	pool := newPool(func(_ context.Context, _ int, data ...interface{}) (interface{}, error) {
+		return nil, run(data...)
+	}, o)
+	pool.errs = make(chan error, errBufSize)
+	pool.dropPolicy = policy
+	pool.fixedRun = "NewPoolErr"
+	return pool
+}
+
+// Get the channel that job errors are sent on, for pools created with
+// NewPoolErr, or nil otherwise
+func (w *WorkerPool) Errors() <-chan error {
+	return w.errs
+}
+
+// Send a job's error on the Errors channel without blocking
+func (w *WorkerPool) reportError(err error) {
+	if w.errs == nil {
+		return
+	}
+
+	w.errsMutex.Lock()
+	defer w.errsMutex.Unlock()
+	for {
+		select {
+		case w.errs <- err:
+			return
+		default:
+		}
+		if w.dropPolicy == DropNewest {
+			return
+		}
+		select {
+		case <-w.errs:
+		default:
+		}
+	}
+}
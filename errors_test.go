@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewPoolErr(t *testing.T) {
+	pool := NewPoolErr(1, 2, DropNewest, func(data ...interface{}) error {
+		if data[0] == nil {
+			return nil
+		}
+		return errors.New(data[0].(string))
+	})
+	defer pool.Stop()
+
+	for _, msg := range []interface{}{"a", nil, "b", "c"} {
+		pool.Run(msg)
+	}
+	pool.Wait()
+
+	if err := <-pool.Errors(); err.Error() != "a" {
+		t.Error("first error should be a, not", err)
+	}
+	if err := <-pool.Errors(); err.Error() != "b" {
+		t.Error("second error should be b, not", err)
+	}
+	if len(pool.Errors()) != 0 {
+		t.Error("the newest error should have been dropped")
+	}
+}
+
+func TestNewPoolErr_DropOldest(t *testing.T) {
+	pool := NewPoolErr(1, 2, DropOldest, func(data ...interface{}) error {
+		return errors.New(data[0].(string))
+	})
+	defer pool.Stop()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		pool.Run(msg)
+	}
+	pool.Wait()
+
+	if err := <-pool.Errors(); err.Error() != "b" {
+		t.Error("first error should be b, not", err)
+	}
+	if err := <-pool.Errors(); err.Error() != "c" {
+		t.Error("second error should be c, not", err)
+	}
+}
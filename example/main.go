@@ -52,34 +52,29 @@ func main() {
 	excess := pool.Excess()
 	fmt.Println("There are", excess, "excess workers (active workers waiting to be stopped)") // 2 excess
 
-	// or, you can implement automatic scaling (see below)
+	// or, you can enable automatic scaling (see below)
 
 	// Automatic worker scaling (not required, but can be useful)
-	go func() {
-		// Make sure to avoid bouncing! Don't upscale with so many workers
-		// that it sets off the downscale operation due to under-usage, or
-		// remove enough workers to set off the upscale operation :)
-		for {
-			// Upscale when the pool is using 95% or more of its workers
-			if float64(pool.Busy()) > float64(pool.Size())*0.95 {
-				// Add 10% more workers
-				// Example: 100 -> 110 -> 121 -> 133 -> 146 -> 160
-				count := float64(pool.Size()) * (1 + 0.10)
-				_ = pool.ScaleUp(int(count))
-			}
-
-			// downscale when the pool is using 50% or less of its workers
-			if float64(pool.Busy()) < float64(pool.Size())*0.50 {
-				// Remove 25% of the workers
-				// Example: 100 -> 75 -> 56 -> 42 -> 31 -> 23
-				count := float64(pool.Size()) * (1 - 0.25)
-				_ = pool.ScaleDown(int(count))
-			}
-
-			// run auto-scaler once per 5 seconds
-			<-time.After(5 * time.Second)
-		}
-	}()
+	stopAutoscale := pool.EnableAutoscale(workers.AutoscaleConfig{
+		// run auto-scaler once per 5 seconds
+		Interval: 5 * time.Second,
+		// Upscale when the pool is using 95% or more of its workers,
+		// adding 10% more workers
+		// Example: 100 -> 110 -> 121 -> 133 -> 146 -> 160
+		UpThreshold: 0.95,
+		UpStep:      0.10,
+		// downscale when the pool is using 50% or less of its workers,
+		// removing 25% of the workers
+		// Example: 100 -> 75 -> 56 -> 42 -> 31 -> 23
+		DownThreshold: 0.50,
+		DownStep:      0.25,
+		// never scale outside of these bounds
+		Min: 1,
+		Max: 100,
+	})
+	// The autoscaler limits each step so that it never sets off the
+	// opposite operation, which avoids bouncing between two sizes
+	defer stopAutoscale()
 
 	_, _ = fmt.Scanln() // Wait for user input before stopping the pool and exiting
 
@@ -0,0 +1,94 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+)
+
+// The eventual result of a job added with SubmitFuture
+type Future struct {
+	// Closed once the job has finished and result and err are set
+	done chan struct{}
+
+	result interface{}
+	err    error
+}
+
+// Add a job to this WorkerPool and get a Future for its result
+//
+// The Future's result is the value returned by the run function for
+// pools created with NewResultPool, which is then not sent on Results,
+// or nil otherwise. Its error is the error returned by the run function
+// for pools created with NewPoolErr, or an error describing the panic
+// if the job panicked.
+//
+// Panics if the pool has been stopped, unless set otherwise with
+// WithClosedPolicy
+func (w *WorkerPool) SubmitFuture(data ...interface{}) *Future {
+	future := &Future{done: make(chan struct{})}
+	job := w.newJob(context.Background(), data)
+	job.future = future
+	if err := w.send(job, nil, true); err != nil {
+		// with PolicyError or PolicyDrop, the future has already
+		// failed with ErrClosed
+		_ = w.onClosed()
+	}
+	return future
+}
+
+// Wait for the job to finish and get its result and error
+//
+// If the pool is stopped before the job runs, the error is ErrClosed.
+func (f *Future) Get() (interface{}, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Get a channel which is closed once the job has finished
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *Future) complete(result interface{}, err error, recovered interface{}) {
+	if recovered != nil {
+		err = fmt.Errorf("the job panicked: %v", recovered)
+	}
+	f.result, f.err = result, err
+	close(f.done)
+}
+
+// Track a job's future while it waits to be run
+func (w *WorkerPool) holdFuture(job job) {
+	if job.future == nil {
+		return
+	}
+	w.waitingFuturesMutex.Lock()
+	if w.waitingFutures == nil {
+		w.waitingFutures = make(map[*Future]struct{})
+	}
+	w.waitingFutures[job.future] = struct{}{}
+	w.waitingFuturesMutex.Unlock()
+}
+
+// Stop tracking a job's future before running or discarding it,
+// returning false if it already failed because the pool was stopped
+func (w *WorkerPool) takeFuture(job job) bool {
+	if job.future == nil {
+		return true
+	}
+	w.waitingFuturesMutex.Lock()
+	defer w.waitingFuturesMutex.Unlock()
+	_, waiting := w.waitingFutures[job.future]
+	delete(w.waitingFutures, job.future)
+	return waiting
+}
+
+// Fail the futures of every job still waiting to be run
+func (w *WorkerPool) failFutures() {
+	w.waitingFuturesMutex.Lock()
+	defer w.waitingFuturesMutex.Unlock()
+	for future := range w.waitingFutures {
+		future.complete(nil, ErrClosed, nil)
+	}
+	w.waitingFutures = nil
+}
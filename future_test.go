@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_SubmitFuture(t *testing.T) {
+	pool := NewResultPool(2, func(data ...interface{}) interface{} {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+		return data[0].(int) * 2
+	})
+	defer pool.Stop()
+
+	future := pool.SubmitFuture(21)
+	<-future.Done()
+	result, err := future.Get()
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if result != 42 {
+		t.Error("result should be 42, not", result)
+	}
+
+	_, err = pool.SubmitFuture("panic").Get()
+	if err == nil {
+		t.Error("a panicking job should have an error")
+	}
+}
+
+func TestWorkerPool_SubmitFuture_Error(t *testing.T) {
+	bad := errors.New("failed")
+	pool := NewPoolErr(1, 1, DropNewest, func(...interface{}) error {
+		return bad
+	})
+	defer pool.Stop()
+
+	result, err := pool.SubmitFuture(1).Get()
+	if err != bad {
+		t.Error("Error should be", bad, "not", err)
+	}
+	if result != nil {
+		t.Error("result should be nil, not", result)
+	}
+}
+
+func TestWorkerPool_SubmitFuture_Stop(t *testing.T) {
+	gate := make(chan struct{})
+	pool := NewBufferedPool(1, 1, func(...interface{}) {
+		<-gate
+	})
+	pool.Run(0)
+	waitBusy(t, pool, 1)
+
+	future := pool.SubmitFuture(1)
+	pool.Stop()
+	select {
+	case <-future.Done():
+	case <-time.After(time.Second):
+		t.Fatal("a queued future should fail when the pool is stopped")
+	}
+	if _, err := future.Get(); err != ErrClosed {
+		t.Error("Error should be", ErrClosed, "not", err)
+	}
+
+	// the job left in the buffer is discarded without completing again
+	close(gate)
+	pool.Restart()
+	pool.Stop()
+}
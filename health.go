@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"time"
+)
+
+// Check the WorkerPool's health once per interval, replacing every
+// worker with a fresh one (as with RecycleWorkers) whenever check
+// returns false
+//
+// Cycling the workers clears any state they hold, such as stale
+// connections set up by WithWorkerStart. The last result is reported
+// by Healthy. Checks stop when the pool is stopped and start again
+// when it is restarted.
+//
+// Panics when interval <= 0
+func WithHealthCheck(interval time.Duration, check func() bool) Option {
+	if interval <= 0 {
+		panic("the interval must be positive")
+	}
+	return func(o *options) {
+		o.healthInterval = interval
+		o.healthCheck = check
+	}
+}
+
+// Get whether the most recent health check passed, which is true
+// before the first check and for pools without WithHealthCheck
+func (w *WorkerPool) Healthy() bool {
+	w.healthyMutex.Lock()
+	defer w.healthyMutex.Unlock()
+	return w.healthy
+}
+
+// Run health checks in the background until ctx is cancelled
+func (w *WorkerPool) startHealthCheck(ctx context.Context) {
+	if w.healthCheck == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(w.healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				healthy := w.healthCheck()
+				w.healthyMutex.Lock()
+				w.healthy = healthy
+				w.healthyMutex.Unlock()
+				if !healthy {
+					w.RecycleWorkers()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
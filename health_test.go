@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithHealthCheck(t *testing.T) {
+	started := make(chan int, 10)
+	results := make(chan bool, 1)
+	results <- false
+	pool := New(func(...interface{}) {},
+		WithSize(2),
+		WithWorkerStart(func(id int) {
+			started <- id
+		}),
+		WithHealthCheck(time.Millisecond, func() bool {
+			select {
+			case healthy := <-results:
+				return healthy
+			default:
+				return true
+			}
+		}),
+	)
+	defer pool.Stop()
+
+	// the initial workers, then their replacements after the failed check
+	for i := 0; i < 4; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("the workers should be cycled after a failed check")
+		}
+	}
+
+	<-time.After(10 * time.Millisecond) // wait for a passing check
+	if !pool.Healthy() {
+		t.Error("pool should be healthy after a passing check")
+	}
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_Healthy(t *testing.T) {
+	pool := New(func(...interface{}) {},
+		WithSize(1),
+		WithHealthCheck(time.Millisecond, func() bool {
+			return false
+		}),
+	)
+	defer pool.Stop()
+	if !pool.Healthy() {
+		t.Error("pool should be healthy before the first check")
+	}
+
+	<-time.After(10 * time.Millisecond)
+	if pool.Healthy() {
+		t.Error("pool should not be healthy after a failed check")
+	}
+}
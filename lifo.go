@@ -0,0 +1,114 @@
+package workers
+
+import "time"
+
+type LIFOPool struct {
+	*condPool
+
+	// The queued jobs, guarded by the pool's mutex
+	stack lifoStack
+}
+
+// Create a new LIFOPool with an initial worker count
+//
+// Workers always take the most recently submitted job, which keeps the
+// latency of new jobs low under bursty load. The cost is that old jobs
+// can starve: while jobs keep arriving faster than they are run, the
+// oldest ones are never picked up. Use SetMaxQueueAge to drop jobs
+// which have waited too long to still be worth running. Jobs are queued
+// in an unbounded stack, so submitting never blocks.
+//
+// Panics when size < 0
+func NewLIFOPool(size int, run RunFunc) *LIFOPool {
+	pool := &LIFOPool{}
+	pool.condPool = newCondPool(size, run, &pool.stack)
+	return pool
+}
+
+// Add a job to this LIFOPool, to be run before every job already queued
+//
+// Jobs submitted after the pool is stopped are discarded.
+func (p *LIFOPool) Run(data ...interface{}) {
+	p.push(func() {
+		p.stack.jobs = append(p.stack.jobs, &lifoJob{
+			queued: time.Now(),
+			args:   data,
+		})
+	})
+}
+
+// Set how long a job may wait in the queue before it is dropped without
+// being run, bounding how long old jobs can starve. Zero (the default)
+// keeps jobs forever
+func (p *LIFOPool) SetMaxQueueAge(maxAge time.Duration) {
+	p.mutex.Lock()
+	p.stack.maxAge = maxAge
+	p.mutex.Unlock()
+}
+
+// Get the number of jobs dropped for waiting longer than the max
+// queue age
+func (p *LIFOPool) Expired() uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stack.expired
+}
+
+// Get the number of jobs waiting in the queue, including any which
+// have passed the max queue age but have not been dropped yet
+func (p *LIFOPool) QueueLen() int {
+	return p.condPool.QueueLen()
+}
+
+// A queued job in a LIFOPool
+type lifoJob struct {
+	queued time.Time
+	args   []interface{}
+}
+
+// A stack of jobs, oldest first, so the newest is taken from the end
+type lifoStack struct {
+	jobs []*lifoJob
+
+	// How long a job may wait before it is dropped, or zero for no
+	// limit, and the number of jobs dropped for waiting too long
+	maxAge  time.Duration
+	expired uint64
+}
+
+func (s *lifoStack) Len() int {
+	return len(s.jobs)
+}
+
+func (s *lifoStack) take() ([]interface{}, bool) {
+	s.dropExpired()
+	if len(s.jobs) == 0 {
+		return nil, false
+	}
+	last := len(s.jobs) - 1
+	job := s.jobs[last]
+	s.jobs[last] = nil
+	s.jobs = s.jobs[:last]
+	return job.args, true
+}
+
+func (s *lifoStack) reset() {
+	s.jobs = nil
+}
+
+// Drop the jobs at the bottom of the stack which have waited longer
+// than the max queue age
+func (s *lifoStack) dropExpired() {
+	if s.maxAge <= 0 {
+		return
+	}
+	// the oldest jobs are first, so stop at the first one still fresh
+	now := time.Now()
+	n := 0
+	for n < len(s.jobs) && now.Sub(s.jobs[n].queued) > s.maxAge {
+		s.jobs[n] = nil
+		n++
+	}
+	s.jobs = s.jobs[n:]
+	s.expired += uint64(n)
+}
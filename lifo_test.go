@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewLIFOPool(t *testing.T) {
+	var mutex sync.Mutex
+	var order []interface{}
+	var wg sync.WaitGroup
+	pool := NewLIFOPool(0, func(data ...interface{}) {
+		mutex.Lock()
+		order = append(order, data[0])
+		mutex.Unlock()
+		wg.Done()
+	})
+	defer pool.Stop()
+
+	wg.Add(3)
+	pool.Run("first")
+	pool.Run("second")
+	pool.Run("third")
+	if pool.QueueLen() != 3 {
+		t.Error("queue length should be 3, not", pool.QueueLen())
+	}
+
+	_ = pool.ScaleUp(1) // a single worker runs jobs in queue order
+	wg.Wait()
+
+	expected := []interface{}{"third", "second", "first"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Error("job", i, "should be", expected[i], "not", order[i])
+		}
+	}
+}
+
+func TestLIFOPool_SetMaxQueueAge(t *testing.T) {
+	ran := make(chan interface{}, 2)
+	pool := NewLIFOPool(0, func(data ...interface{}) {
+		ran <- data[0]
+	})
+	defer pool.Stop()
+	pool.SetMaxQueueAge(5 * time.Millisecond)
+
+	pool.Run("old")
+	<-time.After(10 * time.Millisecond)
+	pool.Run("new")
+
+	_ = pool.ScaleUp(1)
+	if job := <-ran; job != "new" {
+		t.Error("the newest job should run, not", job)
+	}
+	select {
+	case job := <-ran:
+		t.Error("a job older than the max queue age should not run, but", job, "did")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if pool.Expired() != 1 {
+		t.Error("expired jobs should equal 1, not", pool.Expired())
+	}
+}
+
+func TestLIFOPool_ScaleDown(t *testing.T) {
+	pool := NewLIFOPool(10, func(...interface{}) {})
+	defer pool.Stop()
+
+	err := pool.ScaleDown(15)
+	if err == nil {
+		t.Error("pool must not accept a higher value when scaling down")
+	}
+
+	err = pool.ScaleDown(5)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 5 {
+		t.Error("pool size should be 5, not", pool.Size())
+	}
+}
+
+func TestLIFOPool_OnPanic(t *testing.T) {
+	pool := NewLIFOPool(1, func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	})
+	defer pool.Stop()
+
+	recovered := make(chan interface{}, 1)
+	pool.OnPanic(func(r interface{}, job []interface{}) {
+		recovered <- r
+	})
+
+	pool.Run("panic")
+	if r := <-recovered; r != "bad job" {
+		t.Error("recovered value should be \"bad job\", not", r)
+	}
+	if pool.Panicked() != 1 {
+		t.Error("panicked jobs should equal 1, not", pool.Panicked())
+	}
+}
@@ -0,0 +1,22 @@
+package workers
+
+// A function which receives the WorkerPool's lifecycle events, along
+// with fields describing each event
+//
+// The events are "job_start", "job_done" and "job_panic", which have a
+// "worker" field with the worker's id, "worker_spawn" and "worker_stop",
+// which also have a "worker" field, and "scale_up" and "scale_down",
+// which have "from" and "to" fields with the old and new sizes. A
+// job_done event for a job which returned an error has an "error" field,
+// and a job_panic event has a "panic" field with the recovered value.
+//
+// The function is called from the pool's goroutines, so it must be safe
+// for concurrent use. It is easily adapted to slog, zap or logrus.
+type LogFunc func(event string, fields map[string]interface{})
+
+// Set a function which is called on the WorkerPool's lifecycle events
+func WithLogger(log LogFunc) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
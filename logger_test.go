@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var mutex sync.Mutex
+	events := make(map[string][]map[string]interface{})
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		switch data[0] {
+		case "panic":
+			panic("bad job")
+		case "error":
+			return errors.New("failed")
+		}
+		return nil
+	}, WithLogger(func(event string, fields map[string]interface{}) {
+		mutex.Lock()
+		events[event] = append(events[event], fields)
+		mutex.Unlock()
+	}))
+
+	pool.Run("ok")
+	pool.Run("error")
+	pool.Run("panic")
+	pool.Wait()
+	_ = pool.ScaleUp(2)
+	pool.StopAndCount()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(events["job_start"]) != 3 {
+		t.Error("job_start events should equal 3, not", len(events["job_start"]))
+	}
+	if len(events["job_done"]) != 2 {
+		t.Error("job_done events should equal 2, not", len(events["job_done"]))
+	} else if events["job_done"][1]["error"] == nil {
+		t.Error("job_done event for a failed job should have an error field")
+	}
+	if len(events["job_panic"]) != 1 {
+		t.Error("job_panic events should equal 1, not", len(events["job_panic"]))
+	}
+	if len(events["scale_up"]) != 1 || events["scale_up"][0]["to"] != 2 {
+		t.Error("scale_up event should go to 2, not", events["scale_up"])
+	}
+	if len(events["scale_down"]) != 1 || events["scale_down"][0]["to"] != 0 {
+		t.Error("scale_down event should go to 0, not", events["scale_down"])
+	}
+	if len(events["worker_spawn"]) != 2 {
+		t.Error("worker_spawn events should equal 2, not", len(events["worker_spawn"]))
+	}
+}
@@ -0,0 +1,218 @@
+package workers
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// The configuration shared by every WorkerPool constructor
+type options struct {
+	size    int
+	bufSize int
+	onPanic func(recovered interface{}, job []interface{})
+	name    string
+
+	idleTimeout time.Duration
+	minWorkers  int
+	jobTimeout  time.Duration
+
+	maxAttempts   int
+	backoff       func(attempt int) time.Duration
+	retryDeadline time.Duration
+	deadLetter    func(data []interface{}, lastErr error, attempts int)
+
+	onWorkerStart func(id int)
+	onWorkerStop  func(id int)
+
+	logger  LogFunc
+	jobHook JobHook
+
+	ewmaAlpha float64
+
+	rateLimit float64
+	burst     int
+
+	onComplete func(data []interface{}, dur time.Duration, recovered interface{})
+
+	closedPolicy ClosedPolicy
+
+	healthInterval time.Duration
+	healthCheck    func() bool
+}
+
+// A configuration option for New
+type Option func(*options)
+
+// Create a new WorkerPool configured with options
+//
+// Without WithSize, the pool starts with one worker per CPU. The other
+// constructors are equivalent to calling New with the matching options.
+//
+// Panics when the size is < 0
+func New(run RunFunc, opts ...Option) *WorkerPool {
+	return newPool(ignoreContext(run), applyOptions(opts))
+}
+
+// Create a new WorkerPool configured with options whose run function
+// receives the context each job was submitted with
+//
+// Panics when the size is < 0
+func NewCtx(run RunFuncCtx, opts ...Option) *WorkerPool {
+	pool := newPool(ignoreError(run), applyOptions(opts))
+	pool.fixedRun = "NewCtx"
+	return pool
+}
+
+func applyOptions(opts []Option) options {
+	o := options{size: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Set the initial number of workers
+func WithSize(size int) Option {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+// Set the size of the job buffer, which allows new jobs to be queued
+// without blocking if all the workers are busy
+func WithBuffer(bufSize int) Option {
+	return func(o *options) {
+		o.bufSize = bufSize
+	}
+}
+
+// Set the function called when the run function panics (see OnPanic)
+func WithPanicHandler(handler func(recovered interface{}, job []interface{})) Option {
+	return func(o *options) {
+		o.onPanic = handler
+	}
+}
+
+// Set the name returned by Name, without adding the pool to the registry
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Stop workers which have waited longer than timeout for a job,
+// shrinking the pool down to the minimum set with WithMinWorkers
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// Set the number of workers which are never stopped for being idle
+func WithMinWorkers(min int) Option {
+	return func(o *options) {
+		o.minWorkers = min
+	}
+}
+
+// Run each job under a context which is cancelled once timeout has
+// elapsed, counting jobs which overrun it in TimedOut
+//
+// Go cannot stop a running goroutine, so the run function must watch
+// the context (see NewCtx) and return when it is done for the timeout
+// to free the worker.
+func WithJobTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.jobTimeout = timeout
+	}
+}
+
+// Set a function called by each worker when it starts, before it takes
+// any jobs, with the worker's id
+//
+// Ids are reused: a new worker takes the lowest id which is not held by
+// a running worker, so they stay between zero and the number of workers.
+func WithWorkerStart(hook func(id int)) Option {
+	return func(o *options) {
+		o.onWorkerStart = hook
+	}
+}
+
+// Set a function called by each worker when it stops, with the same id
+// that was passed to the WithWorkerStart hook
+//
+// The id is not given to a new worker until the hook returns.
+func WithWorkerStop(hook func(id int)) Option {
+	return func(o *options) {
+		o.onWorkerStop = hook
+	}
+}
+
+// A function called by a worker before running each job, which returns
+// the context to run the job with and a function to call once the job
+// has finished, with the error it returned and the value recovered if it
+// panicked
+//
+// Hooks let other packages, such as tracing integrations, wrap every job
+// without changing the run function.
+type JobHook func(ctx context.Context, data []interface{}) (context.Context, func(err error, recovered interface{}))
+
+// Set a function called around every job (see JobHook)
+func WithJobHook(hook JobHook) Option {
+	return func(o *options) {
+		o.jobHook = hook
+	}
+}
+
+// Make AvgDuration an exponentially weighted moving average, where each
+// new job duration is given a weight of alpha, between 0 and 1
+//
+// Larger values make the average follow recent jobs more closely.
+//
+// Panics when alpha is not between 0 and 1
+func WithDurationEWMA(alpha float64) Option {
+	if alpha <= 0 || alpha > 1 {
+		panic("alpha must be between 0 and 1")
+	}
+	return func(o *options) {
+		o.ewmaAlpha = alpha
+	}
+}
+
+// Set a function called after every job with the job's arguments, how
+// long it ran, and the value recovered if it panicked
+//
+// It is called before the job counts as finished, so after Wait returns
+// it has been called for every job.
+func WithOnComplete(callback func(data []interface{}, dur time.Duration, recovered interface{})) Option {
+	return func(o *options) {
+		o.onComplete = callback
+	}
+}
+
+// What happens when a job is submitted to a stopped WorkerPool
+type ClosedPolicy int
+
+const (
+	// Panic with ErrClosed
+	PolicyPanic ClosedPolicy = iota
+
+	// Return ErrClosed from methods which return an error, and panic
+	// with it from those which cannot
+	PolicyError
+
+	// Discard the job without reporting anything
+	PolicyDrop
+)
+
+// Set what happens when a job is submitted to a stopped WorkerPool,
+// which by default is PolicyPanic
+//
+// TrySubmit returns false under PolicyError and PolicyDrop, and futures
+// from SubmitFuture fail with ErrClosed.
+func WithClosedPolicy(policy ClosedPolicy) Option {
+	return func(o *options) {
+		o.closedPolicy = policy
+	}
+}
@@ -0,0 +1,230 @@
+package workers
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	pool := New(func(...interface{}) {})
+	defer pool.Stop()
+
+	if pool.Size() != runtime.NumCPU() {
+		t.Error("pool size should default to", runtime.NumCPU(), "not", pool.Size())
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	recovered := make(chan interface{}, 1)
+	pool := New(func(...interface{}) {
+		panic("bad job")
+	},
+		WithSize(2),
+		WithBuffer(5),
+		WithName("options-test"),
+		WithPanicHandler(func(r interface{}, job []interface{}) {
+			recovered <- r
+		}),
+	)
+	defer pool.Stop()
+
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+	if pool.QueueCap() != 5 {
+		t.Error("queue capacity should be 5, not", pool.QueueCap())
+	}
+	if pool.Name() != "options-test" {
+		t.Error("pool name should be options-test, not", pool.Name())
+	}
+	if PoolByName("options-test") != nil {
+		t.Error("WithName should not register the pool")
+	}
+
+	pool.Run(1)
+	if r := <-recovered; r != "bad job" {
+		t.Error("recovered value should be \"bad job\", not", r)
+	}
+}
+
+func TestWithIdleTimeout(t *testing.T) {
+	pool := New(func(...interface{}) {},
+		WithSize(5),
+		WithIdleTimeout(time.Millisecond),
+		WithMinWorkers(2),
+	)
+	defer pool.Stop()
+
+	deadline := time.After(time.Second)
+	for pool.Size() != 2 {
+		select {
+		case <-deadline:
+			t.Fatal("pool size should shrink to 2, not", pool.Size())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	<-time.After(5 * time.Millisecond)
+	if pool.Size() != 2 {
+		t.Error("pool size should not shrink below 2, not", pool.Size())
+	}
+	pool.Run(1) // the remaining workers still run jobs
+}
+
+func TestWithJobTimeout(t *testing.T) {
+	pool := NewCtx(func(ctx context.Context, data ...interface{}) {
+		if data[0] == "slow" {
+			<-ctx.Done()
+		}
+	}, WithSize(2), WithJobTimeout(time.Millisecond))
+	defer pool.Stop()
+
+	pool.Run("slow")
+	pool.Run("fast")
+	pool.Wait()
+
+	if pool.TimedOut() != 1 {
+		t.Error("timed out jobs should equal 1, not", pool.TimedOut())
+	}
+	if pool.Processed() != 2 {
+		t.Error("processed jobs should equal 2, not", pool.Processed())
+	}
+}
+
+func TestWithWorkerStart(t *testing.T) {
+	started := make(chan int, 10)
+	stopped := make(chan int, 10)
+	pool := New(func(...interface{}) {},
+		WithSize(3),
+		WithWorkerStart(func(id int) {
+			started <- id
+		}),
+		WithWorkerStop(func(id int) {
+			stopped <- id
+		}),
+	)
+	expectIDs := func() {
+		seen := make(map[int]bool)
+		for i := 0; i < 3; i++ {
+			seen[<-started] = true
+		}
+		for id := 0; id < 3; id++ {
+			if !seen[id] {
+				t.Error("a worker should have started with id", id)
+			}
+		}
+	}
+	expectIDs()
+
+	// the old workers' ids are free again once Restart returns
+	pool.Stop()
+	pool.Restart()
+	for i := 0; i < 3; i++ {
+		<-stopped
+	}
+	expectIDs()
+	pool.Stop()
+}
+
+func TestWithJobHook(t *testing.T) {
+	type key struct{}
+	finished := make(chan interface{}, 1)
+	pool := NewCtx(func(ctx context.Context, data ...interface{}) {
+		if ctx.Value(key{}) != "hooked" {
+			t.Error("job should run with the hook's context")
+		}
+		panic("bad job")
+	}, WithSize(1), WithJobHook(func(ctx context.Context, data []interface{}) (context.Context, func(error, interface{})) {
+		return context.WithValue(ctx, key{}, "hooked"), func(err error, recovered interface{}) {
+			finished <- recovered
+		}
+	}))
+	defer pool.Stop()
+
+	pool.Run(1)
+	if recovered := <-finished; recovered != "bad job" {
+		t.Error("hook should receive the recovered panic, not", recovered)
+	}
+}
+
+func TestWithDurationEWMA(t *testing.T) {
+	pool := New(func(data ...interface{}) {
+		<-time.After(data[0].(time.Duration))
+	}, WithSize(1), WithDurationEWMA(0.9))
+	defer pool.Stop()
+
+	pool.Run(time.Millisecond)
+	pool.Run(20 * time.Millisecond)
+	pool.Wait()
+
+	// the plain average would be about 10.5ms
+	if avg := pool.AvgDuration(); avg < 15*time.Millisecond {
+		t.Error("moving average should favor the recent job, not", avg)
+	}
+}
+
+func TestWithOnComplete(t *testing.T) {
+	var mutex sync.Mutex
+	completed := make(map[interface{}]interface{})
+	pool := New(func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	}, WithSize(2), WithOnComplete(func(data []interface{}, dur time.Duration, recovered interface{}) {
+		mutex.Lock()
+		completed[data[0]] = recovered
+		mutex.Unlock()
+	}))
+	defer pool.Stop()
+
+	pool.Run("ok")
+	pool.Run("panic")
+	pool.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(completed) != 2 {
+		t.Error("completed jobs should equal 2, not", len(completed))
+	}
+	if completed["ok"] != nil {
+		t.Error("successful job should have no recovered value, not", completed["ok"])
+	}
+	if completed["panic"] != "bad job" {
+		t.Error("panicking job should have the recovered value, not", completed["panic"])
+	}
+}
+
+func TestWithClosedPolicy(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(1), WithClosedPolicy(PolicyError))
+	pool.Stop()
+	err := pool.RunCtx(context.Background(), 1)
+	if err != ErrClosed {
+		t.Error("Error should be", ErrClosed, "not", err)
+	}
+	if pool.TrySubmit(1) {
+		t.Error("a stopped pool should not accept jobs")
+	}
+	if _, err = pool.SubmitFuture(1).Get(); err != ErrClosed {
+		t.Error("Error should be", ErrClosed, "not", err)
+	}
+
+	pool = New(func(...interface{}) {}, WithSize(1), WithClosedPolicy(PolicyDrop))
+	pool.Stop()
+	pool.Run(1) // must not panic
+	err = pool.RunCtx(context.Background(), 1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+
+	pool = New(func(...interface{}) {}, WithSize(1))
+	pool.Stop()
+	defer func() {
+		if recover() != ErrClosed {
+			t.Error("Run should panic with", ErrClosed)
+		}
+	}()
+	pool.Run(1)
+}
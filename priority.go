@@ -0,0 +1,97 @@
+package workers
+
+import "container/heap"
+
+type PriorityPool struct {
+	*condPool
+
+	// The queued jobs, ordered by priority and then submission order,
+	// guarded by the pool's mutex
+	queue priorityQueue
+}
+
+// Create a new PriorityPool with an initial worker count
+//
+// Workers always take the highest priority job available, and jobs of
+// equal priority in the order they were submitted. Jobs are queued in
+// an unbounded heap, so submitting never blocks.
+//
+// Panics when size < 0
+func NewPriorityPool(size int, run RunFunc) *PriorityPool {
+	pool := &PriorityPool{}
+	pool.condPool = newCondPool(size, run, &pool.queue)
+	return pool
+}
+
+// Add a job to this PriorityPool with the default priority of zero
+func (p *PriorityPool) Run(data ...interface{}) {
+	p.RunP(0, data...)
+}
+
+// Add a job to this PriorityPool with a priority, where higher
+// priorities are run first
+//
+// Jobs submitted after the pool is stopped are discarded.
+func (p *PriorityPool) RunP(priority int, data ...interface{}) {
+	p.push(func() {
+		heap.Push(&p.queue, &priorityJob{
+			priority: priority,
+			seq:      p.queue.seq,
+			args:     data,
+		})
+		p.queue.seq++
+	})
+}
+
+// A queued job in a PriorityPool
+type priorityJob struct {
+	priority int
+	seq      uint64
+	args     []interface{}
+}
+
+// A max-heap of jobs by priority, breaking ties by submission order
+type priorityQueue struct {
+	jobs []*priorityJob
+
+	// The submission counter used to order jobs of equal priority
+	seq uint64
+}
+
+func (q *priorityQueue) Len() int {
+	return len(q.jobs)
+}
+
+func (q *priorityQueue) Less(i, j int) bool {
+	if q.jobs[i].priority != q.jobs[j].priority {
+		return q.jobs[i].priority > q.jobs[j].priority
+	}
+	return q.jobs[i].seq < q.jobs[j].seq
+}
+
+func (q *priorityQueue) Swap(i, j int) {
+	q.jobs[i], q.jobs[j] = q.jobs[j], q.jobs[i]
+}
+
+func (q *priorityQueue) Push(x interface{}) {
+	q.jobs = append(q.jobs, x.(*priorityJob))
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	n := len(q.jobs)
+	job := q.jobs[n-1]
+	q.jobs[n-1] = nil
+	q.jobs = q.jobs[:n-1]
+	return job
+}
+
+func (q *priorityQueue) take() ([]interface{}, bool) {
+	if len(q.jobs) == 0 {
+		return nil, false
+	}
+	return heap.Pop(q).(*priorityJob).args, true
+}
+
+func (q *priorityQueue) reset() {
+	q.jobs = nil
+}
@@ -0,0 +1,79 @@
+package workers
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNewPriorityPool(t *testing.T) {
+	var mutex sync.Mutex
+	var order []interface{}
+	var wg sync.WaitGroup
+	pool := NewPriorityPool(0, func(data ...interface{}) {
+		mutex.Lock()
+		order = append(order, data[0])
+		mutex.Unlock()
+		wg.Done()
+	})
+	defer pool.Stop()
+
+	wg.Add(5)
+	pool.RunP(1, "low 1")
+	pool.RunP(5, "high 1")
+	pool.Run("default")
+	pool.RunP(5, "high 2")
+	pool.RunP(1, "low 2")
+	if pool.QueueLen() != 5 {
+		t.Error("queue length should be 5, not", pool.QueueLen())
+	}
+
+	_ = pool.ScaleUp(1) // a single worker runs jobs in queue order
+	wg.Wait()
+
+	expected := []interface{}{"high 1", "high 2", "low 1", "low 2", "default"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Error("job", i, "should be", expected[i], "not", order[i])
+		}
+	}
+}
+
+func TestPriorityPool_ScaleDown(t *testing.T) {
+	pool := NewPriorityPool(10, func(...interface{}) {})
+	defer pool.Stop()
+
+	err := pool.ScaleDown(15)
+	if err == nil {
+		t.Error("pool must not accept a higher value when scaling down")
+	}
+
+	err = pool.ScaleDown(5)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 5 {
+		t.Error("pool size should be 5, not", pool.Size())
+	}
+}
+
+func TestPriorityPool_OnPanic(t *testing.T) {
+	pool := NewPriorityPool(1, func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	})
+	defer pool.Stop()
+
+	recovered := make(chan interface{}, 1)
+	pool.OnPanic(func(r interface{}, job []interface{}) {
+		recovered <- r
+	})
+
+	pool.Run("panic")
+	if r := <-recovered; r != "bad job" {
+		t.Error("recovered value should be \"bad job\", not", r)
+	}
+	if pool.Panicked() != 1 {
+		t.Error("panicked jobs should equal 1, not", pool.Panicked())
+	}
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A token bucket shared by a WorkerPool's workers
+type limiter struct {
+	// The number of tokens added per second, and the most the bucket holds
+	rate  float64
+	burst float64
+
+	// The tokens in the bucket as of last, which go negative while
+	// workers are waiting for tokens they have reserved
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func newLimiter(perSecond float64, burst int) *limiter {
+	return &limiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Take a token, waiting until one is available. Returns false if the
+// context is cancelled first
+func (l *limiter) wait(ctx context.Context) bool {
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	deficit := -l.tokens
+	l.mutex.Unlock()
+
+	if deficit <= 0 {
+		return true
+	}
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Limit how many jobs start per second across every worker, allowing
+// bursts of up to burst jobs, without changing the number of workers
+//
+// Workers wait for their turn after taking a job, and count as busy
+// while they wait, so the job buffer fills up as usual while the pool
+// is throttled.
+//
+// Panics when perSecond <= 0 or burst < 1
+func WithRateLimit(perSecond float64, burst int) Option {
+	if perSecond <= 0 || burst < 1 {
+		panic("the rate must be positive and the burst at least one")
+	}
+	return func(o *options) {
+		o.rateLimit = perSecond
+		o.burst = burst
+	}
+}
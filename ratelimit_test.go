@@ -0,0 +1,25 @@
+package workers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(4), WithBuffer(10), WithRateLimit(200, 2))
+	defer pool.Stop()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		pool.Run(i)
+	}
+	pool.Wait()
+
+	// the first 2 jobs start at once, then one every 5ms
+	if elapsed := time.Since(start); elapsed < 18*time.Millisecond {
+		t.Error("6 jobs should take at least 20ms to start, not", elapsed)
+	}
+	if pool.Processed() != 6 {
+		t.Error("processed jobs should equal 6, not", pool.Processed())
+	}
+}
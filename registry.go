@@ -0,0 +1,81 @@
+package workers
+
+import (
+	"sort"
+	"sync"
+)
+
+// The package-level registry of named pools
+var (
+	registry      = make(map[string]*WorkerPool)
+	registryMutex sync.Mutex
+)
+
+// Create a new WorkerPool with an initial worker count and register
+// it under the given name so it can be found with Pools and PoolByName
+//
+// The pool is removed from the registry when it is stopped.
+//
+// Panics when size < 0 or when the name is already registered
+func NewRegisteredPool(name string, size int, run RunFunc) *WorkerPool {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("a pool named " + name + " is already registered")
+	}
+	pool := NewPool(size, run)
+	pool.name = name
+	registry[name] = pool
+	return pool
+}
+
+// Get all of the registered pools, sorted by name
+func Pools() []*WorkerPool {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	pools := make([]*WorkerPool, 0, len(registry))
+	for _, pool := range registry {
+		pools = append(pools, pool)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].name < pools[j].name
+	})
+	return pools
+}
+
+// Get the registered pool with the given name, or nil if there is none
+func PoolByName(name string) *WorkerPool {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	return registry[name]
+}
+
+// Get the name this WorkerPool was registered with, or an empty
+// string if it was not created with NewRegisteredPool
+func (w *WorkerPool) Name() string {
+	return w.name
+}
+
+// Add this WorkerPool back to the registry after a restart, unless its
+// name has been taken by another pool in the meantime
+func (w *WorkerPool) register() {
+	if w.name == "" {
+		return
+	}
+	registryMutex.Lock()
+	if _, ok := registry[w.name]; !ok {
+		registry[w.name] = w
+	}
+	registryMutex.Unlock()
+}
+
+func (w *WorkerPool) deregister() {
+	if w.name == "" {
+		return
+	}
+	registryMutex.Lock()
+	if registry[w.name] == w {
+		delete(registry, w.name)
+	}
+	registryMutex.Unlock()
+}
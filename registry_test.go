@@ -0,0 +1,37 @@
+package workers
+
+import "testing"
+
+func TestNewRegisteredPool(t *testing.T) {
+	pool := NewRegisteredPool("registry-test", 2, func(...interface{}) {})
+
+	if PoolByName("registry-test") != pool {
+		t.Error("pool should be registered as registry-test")
+	}
+	found := false
+	for _, p := range Pools() {
+		if p == pool {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("pool should be listed by Pools()")
+	}
+
+	pool.Stop()
+	if PoolByName("registry-test") != nil {
+		t.Error("pool should be deregistered after Stop")
+	}
+}
+
+func TestNewRegisteredPool_Duplicate(t *testing.T) {
+	pool := NewRegisteredPool("registry-dup", 1, func(...interface{}) {})
+	defer pool.Stop()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate name should panic")
+		}
+	}()
+	NewRegisteredPool("registry-dup", 1, func(...interface{}) {})
+}
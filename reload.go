@@ -0,0 +1,81 @@
+package workers
+
+import "sync/atomic"
+
+// A run function and the number of submitted jobs which will use it
+type generation struct {
+	// The runFunc, which SetRunFunc may replace while jobs are queued
+	run atomic.Value
+
+	// The number of jobs submitted with this generation which have
+	// not finished yet, guarded by the pool's genMutex
+	pending int
+
+	// Whether newer jobs are submitted with a different generation
+	retired bool
+
+	// Closed once this generation is retired and has no pending jobs
+	drained chan struct{}
+}
+
+func newGeneration(run runFunc) *generation {
+	gen := &generation{drained: make(chan struct{})}
+	gen.run.Store(run)
+	return gen
+}
+
+// Get the run function for a job which is being picked up
+func (g *generation) handler() runFunc {
+	return g.run.Load().(runFunc)
+}
+
+// Replace the WorkerPool's run function without stopping the pool or
+// dropping any queued jobs
+//
+// Jobs submitted before the call run with the old function, and jobs
+// submitted from the moment it is called run with newRun. Blocks until
+// every job using the old function has finished, or the pool is stopped.
+//
+// Panics for pools whose run function is not a RunFunc, such as those
+// created with NewPoolErr, NewResultPool, NewCtx or NewPoolWithID, since
+// a RunFunc would silently drop their errors, results, context or ids
+func (w *WorkerPool) ReloadHandler(newRun RunFunc) {
+	w.checkReplaceable()
+	w.genMutex.Lock()
+	old := w.gen
+	old.retired = true
+	if old.pending == 0 {
+		close(old.drained)
+	}
+	w.gen = newGeneration(ignoreContext(newRun))
+	w.genMutex.Unlock()
+
+	select {
+	case <-old.drained:
+	case <-w.Done():
+	}
+}
+
+// Replace the WorkerPool's run function without stopping the pool or
+// waiting for any jobs
+//
+// Unlike ReloadHandler, the function is chosen when a worker picks a job
+// up rather than when it is submitted: jobs which are already running
+// finish with the old function, and every job started afterward, even
+// one queued before the call, runs with run. Jobs submitted before an
+// earlier ReloadHandler call keep the function they were submitted with.
+//
+// Panics for pools whose run function is not a RunFunc, like
+// ReloadHandler
+func (w *WorkerPool) SetRunFunc(run RunFunc) {
+	w.checkReplaceable()
+	w.genMutex.Lock()
+	w.gen.run.Store(ignoreContext(run))
+	w.genMutex.Unlock()
+}
+
+func (w *WorkerPool) checkReplaceable() {
+	if w.fixedRun != "" {
+		panic("the run function of a pool created with " + w.fixedRun + " cannot be replaced with a RunFunc")
+	}
+}
@@ -0,0 +1,128 @@
+package workers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_ReloadHandler(t *testing.T) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	ran := make(map[interface{}]string)
+	record := func(handler string) RunFunc {
+		return func(data ...interface{}) {
+			mutex.Lock()
+			ran[data[0]] = handler
+			mutex.Unlock()
+			wg.Done()
+		}
+	}
+	wg.Add(6)
+
+	gate := make(chan struct{})
+	old := record("old")
+	pool := NewBufferedPool(1, 10, func(data ...interface{}) {
+		<-gate
+		old(data...)
+	})
+	for i := 0; i < 3; i++ {
+		pool.Run(i)
+	}
+	pool.genMutex.Lock()
+	first := pool.gen
+	pool.genMutex.Unlock()
+
+	reloaded := make(chan struct{})
+	go func() {
+		pool.ReloadHandler(record("new"))
+		close(reloaded)
+	}()
+
+	// wait for the new handler to be swapped in, then
+	// submit jobs while the old backlog is still draining
+	for {
+		pool.genMutex.Lock()
+		swapped := pool.gen != first
+		pool.genMutex.Unlock()
+		if swapped {
+			break
+		}
+		<-time.After(time.Microsecond)
+	}
+	for i := 3; i < 6; i++ {
+		pool.Run(i)
+	}
+
+	select {
+	case <-reloaded:
+		t.Fatal("ReloadHandler should block while the old backlog is draining")
+	default:
+	}
+	close(gate)
+	<-reloaded
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i := 0; i < 3; i++ {
+		if ran[i] != "old" {
+			t.Error("job", i, "should run with the old handler, not", ran[i])
+		}
+	}
+	for i := 3; i < 6; i++ {
+		if ran[i] != "new" {
+			t.Error("job", i, "should run with the new handler, not", ran[i])
+		}
+	}
+}
+
+func TestWorkerPool_SetRunFunc(t *testing.T) {
+	started := make(chan bool)
+	gate := make(chan bool)
+	ran := make(chan string, 3)
+	pool := NewBufferedPool(1, 5, func(...interface{}) {
+		started <- true
+		<-gate
+		ran <- "old"
+	})
+	defer pool.Stop()
+
+	pool.Run(1)
+	<-started
+	pool.Run(2) // queued before the change
+	pool.SetRunFunc(func(...interface{}) {
+		ran <- "new"
+	})
+	pool.Run(3)
+	close(gate)
+	pool.Wait()
+
+	for _, expected := range []string{"old", "new", "new"} {
+		if handler := <-ran; handler != expected {
+			t.Error("job should run with the", expected, "handler, not", handler)
+		}
+	}
+}
+
+func TestWorkerPool_SetRunFunc_Fixed(t *testing.T) {
+	pool := NewPoolErr(1, 1, DropNewest, func(...interface{}) error {
+		return nil
+	})
+	defer pool.Stop()
+
+	expectPanic := func(name string, replace func()) {
+		defer func() {
+			if recover() == nil {
+				t.Error(name, "should panic for a pool created with NewPoolErr")
+			}
+		}()
+		replace()
+	}
+	expectPanic("SetRunFunc", func() {
+		pool.SetRunFunc(func(...interface{}) {})
+	})
+	expectPanic("ReloadHandler", func() {
+		pool.ReloadHandler(func(...interface{}) {})
+	})
+}
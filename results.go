@@ -0,0 +1,65 @@
+package workers
+
+import "context"
+
+type ResultFunc func(...interface{}) interface{}
+
+// Create a new WorkerPool with an initial worker count whose run
+// function returns a value, which is sent on the Results channel
+//
+// The Results channel is unbuffered, so each worker blocks after
+// running a job until its result has been received. If nothing reads
+// the results, every worker will eventually block and no more jobs
+// will be started.
+//
+// Panics when size < 0
+func NewResultPool(size int, run ResultFunc) *WorkerPool {
+	pool := newPool(func(_ context.Context, _ int, data ...interface{}) (interface{}, error) {
+		return run(data...), nil
+	}, options{size: size})
+	pool.results = make(chan interface{})
+	pool.fixedRun = "NewResultPool"
+	return pool
+}
+
+// Get the channel that results are sent on, for pools created with
+// NewResultPool, or nil otherwise
+func (w *WorkerPool) Results() <-chan interface{} {
+	return w.results
+}
+
+// Forward every result from src, which must be created with
+// NewResultPool, to dst as a job with the result as its only argument
+//
+// Results are forwarded in the order they are produced, which with more
+// than one worker in src is not the order jobs were submitted in.
+// Forwarding blocks like Run while dst is full, which in turn holds up
+// src's workers.
+//
+// Forwarding stops when src is stopped, or if dst is stopped, and the
+// returned channel is then closed. Stopping src does not stop dst; to
+// shut a pipeline down without losing results, call src.DrainAndStop,
+// wait for the returned channel, then call dst.DrainAndStop.
+//
+// Panics when src has no results channel
+func Pipe(src, dst *WorkerPool) <-chan struct{} {
+	if src.results == nil {
+		panic("src must be created with NewResultPool")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case result := <-src.results:
+				job := dst.newJob(context.Background(), []interface{}{result})
+				if dst.send(job, nil, true) == ErrClosed {
+					return
+				}
+			case <-src.Done():
+				return
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewResultPool(t *testing.T) {
+	pool := NewResultPool(3, func(data ...interface{}) interface{} {
+		return data[0].(int) * 2
+	})
+	defer pool.Stop()
+
+	go func() {
+		for i := 1; i <= 3; i++ {
+			pool.Run(i)
+		}
+	}()
+
+	sum := 0
+	for i := 0; i < 3; i++ {
+		sum += (<-pool.Results()).(int)
+	}
+	if sum != 12 {
+		t.Error("sum of results should be 12, not", sum)
+	}
+}
+
+func TestNewResultPool_DrainAndStop(t *testing.T) {
+	gate := make(chan struct{})
+	pool := NewResultPool(1, func(data ...interface{}) interface{} {
+		<-gate
+		return data[0]
+	})
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+	pool.Run(1)
+	go pool.Run(2) // blocks until the worker is free
+	<-time.After(time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		pool.DrainAndStop()
+		close(done)
+	}()
+	<-time.After(time.Millisecond) // let DrainAndStop wait for the blocked Run
+	close(gate)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("DrainAndStop should return once both jobs have run")
+	}
+}
+
+func TestWorkerPool_Results(t *testing.T) {
+	pool := NewPool(1, func(...interface{}) {})
+	if pool.Results() != nil {
+		t.Error("plain pools should not have a results channel")
+	}
+}
+
+func TestPipe(t *testing.T) {
+	src := NewResultPool(2, func(data ...interface{}) interface{} {
+		return data[0].(int) * 2
+	})
+	var sum int64
+	dst := NewBufferedPool(2, 5, func(data ...interface{}) {
+		atomic.AddInt64(&sum, int64(data[0].(int)))
+	})
+	done := Pipe(src, dst)
+
+	for i := 1; i <= 4; i++ {
+		src.Run(i)
+	}
+	src.DrainAndStop()
+	<-done
+	dst.DrainAndStop()
+
+	if atomic.LoadInt64(&sum) != 20 {
+		t.Error("sum of forwarded results should be 20, not", atomic.LoadInt64(&sum))
+	}
+}
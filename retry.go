@@ -0,0 +1,98 @@
+package workers
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Run jobs which return an error again, up to maxAttempts times in
+// total, waiting backoff(attempt) after the attempt-th failure
+//
+// Only applies to pools whose run function returns an error (see
+// NewPoolErr). Jobs waiting to be retried do not use a worker. They are
+// still retried while DrainAndStop or Shutdown waits for the pool to
+// drain, but if the pool is stopped they are given up on as though they
+// had no attempts left. Only the error from the last attempt is sent on
+// the Errors channel.
+func WithRetry(maxAttempts int, backoff func(attempt int) time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// Stop retrying a job once the deadline has passed since its first
+// run, even if it has attempts left
+//
+// Used together with WithRetry. A retry which could not start before
+// the deadline because of its backoff is not attempted. A job which
+// runs out of time is treated like one which runs out of attempts,
+// and is passed to the WithDeadLetter function.
+func WithRetryDeadline(deadline time.Duration) Option {
+	return func(o *options) {
+		o.retryDeadline = deadline
+	}
+}
+
+// Set a function called with every job which has failed and will not
+// be retried, its last error, and how many times it ran
+//
+// Use it to keep failed jobs somewhere durable for later inspection
+// instead of dropping them. It is called before the job counts as
+// finished, so after Wait returns it has been called for every job.
+func WithDeadLetter(handler func(data []interface{}, lastErr error, attempts int)) Option {
+	return func(o *options) {
+		o.deadLetter = handler
+	}
+}
+
+// Get the number of times a failed job has been queued to run again
+func (w *WorkerPool) Retried() uint64 {
+	return atomic.LoadUint64(&w.retried)
+}
+
+// Queue a failed job to run again after its backoff, returning false
+// if it has no attempts or time left
+//
+// If the pool is stopped (done is closed) before the job runs again, the
+// job is given up on with lastErr as though it had no attempts left.
+func (w *WorkerPool) retry(job job, lastErr error, done <-chan struct{}) bool {
+	job.attempts++
+	if job.attempts >= w.maxAttempts {
+		return false
+	}
+
+	var delay time.Duration
+	if w.backoff != nil {
+		delay = w.backoff(job.attempts)
+	}
+	if w.retryDeadline > 0 && time.Since(job.firstRun)+delay >= w.retryDeadline {
+		return false
+	}
+	atomic.AddUint64(&w.retried, 1)
+	w.holdFuture(job)
+	go func() {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			// send discards the job itself if the pool has stopped
+			if w.send(job, nil, true) != nil {
+				w.giveUp(job, lastErr)
+			}
+		case <-done:
+			timer.Stop()
+			w.giveUp(job, lastErr)
+			w.discard(job)
+		}
+	}()
+	return true
+}
+
+// Report a discarded retry's last error like that of a job which ran
+// out of attempts
+func (w *WorkerPool) giveUp(job job, lastErr error) {
+	if w.deadLetter != nil {
+		w.deadLetter(job.args, lastErr, job.attempts)
+	}
+	w.reportError(lastErr)
+}
@@ -0,0 +1,193 @@
+package workers
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	var calls int32
+	var backoffs []int
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return time.Millisecond
+	}))
+	defer pool.Stop()
+
+	pool.Run(1)
+	pool.Wait()
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("job should run 3 times, not", atomic.LoadInt32(&calls))
+	}
+	if pool.Retried() != 2 {
+		t.Error("retries should equal 2, not", pool.Retried())
+	}
+	if len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Error("backoff should be called for attempts 1 and 2, not", backoffs)
+	}
+	if len(pool.Errors()) != 0 {
+		t.Error("a job which eventually succeeds should not report an error")
+	}
+}
+
+func TestWithRetry_Exhausted(t *testing.T) {
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		return errors.New("always")
+	}, WithRetry(3, nil))
+	defer pool.Stop()
+
+	pool.Run(1)
+	pool.Wait()
+
+	if pool.Retried() != 2 {
+		t.Error("retries should equal 2, not", pool.Retried())
+	}
+	if err := <-pool.Errors(); err.Error() != "always" {
+		t.Error("the last error should be reported, not", err)
+	}
+}
+
+func TestWithRetry_DrainAndStop_Blocked(t *testing.T) {
+	var calls int32
+	gate := make(chan struct{})
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-gate
+			return errors.New("not yet")
+		}
+		return nil
+	}, WithRetry(3, nil))
+	pool.Run(1)
+	go pool.Run(2) // blocks until the worker is free
+	<-time.After(time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		pool.DrainAndStop()
+		close(done)
+	}()
+	<-time.After(time.Millisecond) // let DrainAndStop wait for the blocked Run
+	close(gate)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("DrainAndStop should return once both jobs and the retry have run")
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("jobs should run 3 times, not", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestWithRetryDeadline(t *testing.T) {
+	var calls int32
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("always")
+	}, WithRetry(100, func(int) time.Duration {
+		return 20 * time.Millisecond
+	}), WithRetryDeadline(50*time.Millisecond))
+	defer pool.Stop()
+
+	pool.Run(1)
+	pool.Wait()
+
+	// runs at 0ms, 20ms and 40ms; a retry at 60ms would pass the deadline
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("job should run 3 times, not", atomic.LoadInt32(&calls))
+	}
+	if pool.Retried() != 2 {
+		t.Error("retries should equal 2, not", pool.Retried())
+	}
+	if err := <-pool.Errors(); err.Error() != "always" {
+		t.Error("the last error should be reported, not", err)
+	}
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	var lastData []interface{}
+	var lastErr error
+	var lastAttempts int
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		if data[0] == "ok" {
+			return nil
+		}
+		return errors.New("always")
+	}, WithRetry(3, nil), WithDeadLetter(func(data []interface{}, err error, attempts int) {
+		lastData, lastErr, lastAttempts = data, err, attempts
+	}))
+	defer pool.Stop()
+
+	pool.Run("ok")
+	pool.Wait()
+	if lastData != nil {
+		t.Error("a job which succeeds should not be dead-lettered")
+	}
+
+	pool.Run("fail")
+	pool.Wait()
+	if len(lastData) != 1 || lastData[0] != "fail" {
+		t.Error("the failed job's data should be passed, not", lastData)
+	}
+	if lastErr == nil || lastErr.Error() != "always" {
+		t.Error("the last error should be passed, not", lastErr)
+	}
+	if lastAttempts != 3 {
+		t.Error("attempts should equal 3, not", lastAttempts)
+	}
+}
+
+func TestWithRetry_DrainAndStop(t *testing.T) {
+	var calls, dead int32
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		<-time.After(20 * time.Millisecond)
+		return errors.New("always")
+	}, WithRetry(3, nil), WithDeadLetter(func([]interface{}, error, int) {
+		atomic.AddInt32(&dead, 1)
+	}))
+
+	pool.Run(1)
+	pool.DrainAndStop()
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("job should run 3 times while draining, not", atomic.LoadInt32(&calls))
+	}
+	if atomic.LoadInt32(&dead) != 1 {
+		t.Error("dead-lettered jobs should equal 1, not", atomic.LoadInt32(&dead))
+	}
+	if len(pool.Errors()) != 1 {
+		t.Error("the last error should be reported")
+	}
+}
+
+func TestWithRetry_Stop(t *testing.T) {
+	var dead int32
+	pool := NewPoolErr(1, 1, DropNewest, func(data ...interface{}) error {
+		return errors.New("always")
+	}, WithRetry(3, func(int) time.Duration {
+		return time.Hour
+	}), WithDeadLetter(func([]interface{}, error, int) {
+		atomic.AddInt32(&dead, 1)
+	}))
+
+	pool.Run(1)
+	<-time.After(10 * time.Millisecond) // wait for the first attempt
+	pool.Stop()
+	<-time.After(10 * time.Millisecond) // wait for the retry to give up
+
+	if atomic.LoadInt32(&dead) != 1 {
+		t.Error("a retry dropped by Stop should be dead-lettered")
+	}
+	if len(pool.Errors()) != 1 {
+		t.Error("the last error of a retry dropped by Stop should be reported")
+	}
+}
@@ -0,0 +1,142 @@
+package workers
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// A consistent snapshot of a WorkerPool's counters
+type Stats struct {
+	// The total number of workers
+	Size int
+
+	// The number of busy workers
+	Busy int
+
+	// The number of workers waiting for jobs
+	Waiting int
+
+	// The number of workers waiting to close
+	Excess int
+
+	// The number of workers detached to run tasks
+	Detached int
+
+	// The number of jobs waiting in the job buffer
+	QueueLen int
+
+	// The number of jobs which have finished running
+	Processed uint64
+
+	// The number of jobs which panicked
+	Panicked uint64
+}
+
+// Get a snapshot of this WorkerPool's counters, all read at once
+//
+// Unlike calling Size, Busy, Waiting and Excess separately, the values
+// are consistent with each other.
+func (w *WorkerPool) Stats() Stats {
+	w.sizeMutex.Lock()
+	w.busyMutex.Lock()
+	w.closingMutex.Lock()
+	w.detachedMutex.Lock()
+	stats := Stats{
+		Size:     w.size,
+		Busy:     w.busy,
+		Excess:   w.closing,
+		Detached: w.detached,
+	}
+	w.detachedMutex.Unlock()
+	w.closingMutex.Unlock()
+	w.busyMutex.Unlock()
+	w.sizeMutex.Unlock()
+
+	stats.Waiting = stats.Size - stats.Busy - stats.Detached
+	if stats.Waiting < 0 {
+		stats.Waiting = 0
+	}
+	stats.QueueLen = w.QueueLen()
+	stats.Processed = atomic.LoadUint64(&w.processed)
+	stats.Panicked = atomic.LoadUint64(&w.panicked)
+	return stats
+}
+
+// Publish this WorkerPool's Stats as an expvar variable, so they can be
+// read from /debug/vars
+//
+// The stats are read each time the variable is. Panics if name is
+// already published, like expvar.Publish.
+func (w *WorkerPool) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		stats := w.Stats()
+		return map[string]interface{}{
+			"size":      stats.Size,
+			"busy":      stats.Busy,
+			"waiting":   stats.Waiting,
+			"excess":    stats.Excess,
+			"detached":  stats.Detached,
+			"queueLen":  stats.QueueLen,
+			"processed": stats.Processed,
+			"panicked":  stats.Panicked,
+		}
+	}))
+}
+
+// What a worker was doing when WorkerStates was called
+type WorkerState struct {
+	// The worker's id (see WithWorkerStart)
+	ID int
+
+	// Whether the worker is running a job
+	Busy bool
+
+	// How long the worker's current job has been running, if it is busy
+	Running time.Duration
+}
+
+// Get the state of every running worker, ordered by id
+//
+// Useful for spotting a hung job which is holding a worker hostage.
+// Detached workers are not included.
+func (w *WorkerPool) WorkerStates() []WorkerState {
+	now := time.Now()
+	w.workerIDsMutex.Lock()
+	defer w.workerIDsMutex.Unlock()
+	var states []WorkerState
+	for id, held := range w.workerIDs {
+		if !held {
+			continue
+		}
+		state := WorkerState{ID: id}
+		if start := w.jobStarts[id]; !start.IsZero() {
+			state.Busy = true
+			state.Running = now.Sub(start)
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// Clear the job counters (Processed, Panicked, TimedOut and Retried),
+// the run time behind AvgDuration, and the peak busy workers and queue
+// length, for clean numbers between benchmark runs
+//
+// The peaks are reset to the current values, as with ResetPeak and
+// ResetPeakQueue. Jobs finishing while the counters are cleared may be
+// counted either before or after the reset.
+func (w *WorkerPool) ResetStats() {
+	atomic.StoreUint64(&w.runTime, 0)
+	atomic.StoreUint64(&w.processed, 0)
+	atomic.StoreUint64(&w.panicked, 0)
+	atomic.StoreUint64(&w.timedOut, 0)
+	atomic.StoreUint64(&w.retried, 0)
+
+	w.ewmaMutex.Lock()
+	w.ewma = 0
+	w.ewmaMutex.Unlock()
+
+	w.ResetPeak()
+	w.ResetPeakQueue()
+}
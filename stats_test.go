@@ -0,0 +1,122 @@
+package workers
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_Stats(t *testing.T) {
+	pool := NewBufferedPool(4, 10, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	for i := 0; i < 5; i++ {
+		pool.Run(i)
+	}
+	waitBusy(t, pool, 4)
+
+	stats := pool.Stats()
+	if stats.Size != 4 {
+		t.Error("size should be 4, not", stats.Size)
+	}
+	if stats.Busy != 4 {
+		t.Error("busy workers should equal 4, not", stats.Busy)
+	}
+	if stats.Waiting != 0 {
+		t.Error("waiting workers should equal 0, not", stats.Waiting)
+	}
+	if stats.QueueLen != 1 {
+		t.Error("queue length should be 1, not", stats.QueueLen)
+	}
+	if stats.Processed != 0 {
+		t.Error("processed jobs should equal 0, not", stats.Processed)
+	}
+}
+
+func TestWorkerPool_PublishExpvar(t *testing.T) {
+	pool := NewPool(3, func(...interface{}) {})
+	defer pool.Stop()
+	pool.Run(1)
+	pool.Wait()
+
+	// expvar names can only be published once per process, so each
+	// run (as with -count) needs its own
+	name := fmt.Sprint(t.Name(), "_", time.Now().UnixNano())
+	pool.PublishExpvar(name)
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("the variable should be published")
+	}
+
+	var stats map[string]int
+	if err := json.Unmarshal([]byte(v.String()), &stats); err != nil {
+		t.Fatal("error should be nil, not", err)
+	}
+	if stats["size"] != 3 {
+		t.Error("size should be 3, not", stats["size"])
+	}
+	if stats["processed"] != 1 {
+		t.Error("processed jobs should equal 1, not", stats["processed"])
+	}
+}
+
+func TestWorkerPool_WorkerStates(t *testing.T) {
+	pool := NewPool(3, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	pool.Run(1)
+	waitBusy(t, pool, 1)
+	<-time.After(time.Millisecond)
+
+	states := pool.WorkerStates()
+	if len(states) != 3 {
+		t.Fatal("worker states should have length 3, not", len(states))
+	}
+	busy := 0
+	for _, state := range states {
+		if state.Busy {
+			busy++
+			if state.Running < time.Millisecond {
+				t.Error("busy worker should have been running for at least 1ms, not", state.Running)
+			}
+		}
+	}
+	if busy != 1 {
+		t.Error("busy workers should equal 1, not", busy)
+	}
+}
+
+func TestWorkerPool_ResetStats(t *testing.T) {
+	pool := New(func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("test")
+		}
+		time.Sleep(time.Millisecond)
+	}, WithSize(2), WithDurationEWMA(0.5))
+	defer pool.Stop()
+	pool.Run("ok")
+	pool.Run("panic")
+	pool.Wait()
+
+	pool.ResetStats()
+	if pool.Processed() != 0 {
+		t.Error("processed jobs should equal 0, not", pool.Processed())
+	}
+	if pool.Panicked() != 0 {
+		t.Error("panicked jobs should equal 0, not", pool.Panicked())
+	}
+	if pool.AvgDuration() != 0 {
+		t.Error("average duration should be 0, not", pool.AvgDuration())
+	}
+	if pool.PeakBusy() != 0 {
+		t.Error("peak busy workers should equal 0, not", pool.PeakBusy())
+	}
+
+	pool.Run("ok")
+	pool.Wait()
+	if pool.Processed() != 1 {
+		t.Error("processed jobs should equal 1, not", pool.Processed())
+	}
+}
@@ -0,0 +1,26 @@
+package workers
+
+// A WorkerPool whose jobs are closures rather than arguments to a
+// fixed run function
+//
+// Every WorkerPool method is available; jobs added with Run must
+// consist of a single func().
+type TaskPool struct {
+	*WorkerPool
+}
+
+// Create a new TaskPool with an initial worker count
+//
+// Panics when size < 0
+func NewTaskPool(size int) *TaskPool {
+	pool := NewPool(size, func(data ...interface{}) {
+		data[0].(func())()
+	})
+	pool.fixedRun = "NewTaskPool"
+	return &TaskPool{WorkerPool: pool}
+}
+
+// Add a task to this TaskPool, to be run by the next free worker
+func (t *TaskPool) Submit(task func()) {
+	t.Run(task)
+}
@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewTaskPool(t *testing.T) {
+	pool := NewTaskPool(3)
+	defer pool.Stop()
+
+	var sum int32
+	for i := int32(1); i <= 4; i++ {
+		n := i
+		pool.Submit(func() {
+			atomic.AddInt32(&sum, n)
+		})
+	}
+	pool.Wait()
+
+	if atomic.LoadInt32(&sum) != 10 {
+		t.Error("sum should be 10, not", atomic.LoadInt32(&sum))
+	}
+}
@@ -0,0 +1,46 @@
+package typed
+
+import (
+	"context"
+	"sync"
+)
+
+// Call f with every element of in using size workers
+//
+// Blocks until every call has returned.
+//
+// Panics when size < 1
+func ForEach[T any](size int, in []T, f func(T)) {
+	Map(size, in, func(v T) struct{} {
+		f(v)
+		return struct{}{}
+	})
+}
+
+// Call f with every element of in using size workers, stopping at the
+// first error
+//
+// Returns the first error f returns. Once an error has been returned,
+// elements which have not started yet are skipped, while calls already
+// running are waited for.
+//
+// Panics when size < 1
+func ForEachErr[T any](size int, in []T, f func(T) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var first error
+	var once sync.Once
+	ForEach(size, in, func(v T) {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := f(v); err != nil {
+			once.Do(func() {
+				first = err
+				cancel()
+			})
+		}
+	})
+	return first
+}
@@ -0,0 +1,45 @@
+package typed
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	var sum int64
+	ForEach(3, []int64{1, 2, 3, 4}, func(n int64) {
+		atomic.AddInt64(&sum, n)
+	})
+	if sum != 10 {
+		t.Error("sum should be 10, not", sum)
+	}
+}
+
+func TestForEachErr(t *testing.T) {
+	var ran int32
+	in := make([]int, 100)
+	for i := range in {
+		in[i] = i
+	}
+
+	bad := errors.New("bad element")
+	err := ForEachErr(1, in, func(n int) error {
+		atomic.AddInt32(&ran, 1)
+		if n == 2 {
+			return bad
+		}
+		return nil
+	})
+	if err != bad {
+		t.Error("Error should be", bad, "not", err)
+	}
+	if ran != 3 {
+		t.Error("elements after the error should be skipped, but", ran, "ran")
+	}
+
+	err = ForEachErr(2, in, func(int) error { return nil })
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+}
@@ -0,0 +1,29 @@
+package typed
+
+import "sync"
+
+// Apply f to every element of in using size workers, and return the
+// results in the same order as in
+//
+// Blocks until every element has been processed.
+//
+// Panics when size < 1
+func Map[T, R any](size int, in []T, f func(T) R) []R {
+	if size < 1 {
+		panic("size must be at least one")
+	}
+	out := make([]R, len(in))
+
+	var wg sync.WaitGroup
+	wg.Add(len(in))
+	pool := NewPool(size, func(i int) {
+		defer wg.Done()
+		out[i] = f(in[i])
+	})
+	for i := range in {
+		pool.Run(i)
+	}
+	wg.Wait()
+	pool.Stop()
+	return out
+}
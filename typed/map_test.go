@@ -0,0 +1,23 @@
+package typed
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5}
+	out := Map(3, in, func(n int) string {
+		return strconv.Itoa(n * n)
+	})
+
+	expected := []string{"1", "4", "9", "16", "25"}
+	if len(out) != len(expected) {
+		t.Fatal("results should have length", len(expected), "not", len(out))
+	}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Error("result", i, "should be", expected[i], "not", out[i])
+		}
+	}
+}
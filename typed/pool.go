@@ -0,0 +1,282 @@
+// Package typed provides a WorkerPool which is generic over its job
+// type, avoiding interface{} boxing and type assertions in run functions
+package typed
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// The error that submitting to a stopped Pool panics with
+var ErrClosed = errors.New("the pool has been stopped")
+
+var errNotSent = errors.New("the job was not accepted")
+
+type RunFunc[T any] func(T)
+
+type Pool[T any] struct {
+	// The worker's run function
+	run RunFunc[T]
+
+	// The channel for workers to listen for jobs, and whether it has
+	// been closed. Submissions hold a read lock while sending
+	jobs       chan T
+	closed     bool
+	closeMutex sync.RWMutex
+
+	// The channel to stop a certain number of workers. It is never
+	// closed; workers also exit when the pool's context is cancelled,
+	// so scaling down concurrently with Stop cannot panic
+	stop chan struct{}
+
+	// The context cancelled when this pool is stopped
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// The size of this worker pool (number of workers)
+	size      int
+	sizeMutex sync.Mutex
+
+	// The number of busy workers in this worker pool
+	busy      int
+	busyMutex sync.Mutex
+
+	// The number of workers waiting to close
+	closing      int
+	closingMutex sync.Mutex
+}
+
+// Create a new Pool with an initial worker count
+//
+// Panics when size < 0
+func NewPool[T any](size int, run RunFunc[T]) *Pool[T] {
+	return NewBufferedPool(size, 0, run)
+}
+
+// Create a new Pool with an initial worker count and job buffer size
+//
+// The job buffer allows new jobs to be queued without blocking if
+// all the workers are busy
+//
+// Panics when size < 0
+func NewBufferedPool[T any](size, bufSize int, run RunFunc[T]) *Pool[T] {
+	if size < 0 {
+		panic("size must be greater than zero")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &Pool[T]{
+		run:    run,
+		jobs:   make(chan T, bufSize),
+		stop:   make(chan struct{}),
+		size:   size,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	// spawn workers up to the limit
+	pool.createWorkers(size)
+	return pool
+}
+
+// Add a job to this Pool
+//
+// Panics with ErrClosed if the pool has been stopped
+func (p *Pool[T]) Run(job T) {
+	if err := p.send(job, nil); err != nil {
+		panic(err)
+	}
+}
+
+// Add many jobs to this Pool, in order
+//
+// Panics with ErrClosed if the pool has been stopped
+func (p *Pool[T]) SubmitBatch(jobs []T) {
+	for _, job := range jobs {
+		p.Run(job)
+	}
+}
+
+// Add many jobs to this Pool, stopping early if the context is
+// cancelled while waiting to submit one
+//
+// Returns the number of jobs submitted, and the context's error if the
+// batch was interrupted.
+func (p *Pool[T]) SubmitBatchCtx(ctx context.Context, jobs []T) (int, error) {
+	for i, job := range jobs {
+		err := p.send(job, ctx.Done())
+		if err == ErrClosed {
+			panic(err)
+		}
+		if err != nil {
+			return i, ctx.Err()
+		}
+	}
+	return len(jobs), nil
+}
+
+// Send a job to the workers, blocking until it is accepted or abort is
+// closed. Returns ErrClosed if the pool is stopped first
+func (p *Pool[T]) send(job T, abort <-chan struct{}) error {
+	p.closeMutex.RLock()
+	defer p.closeMutex.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-abort:
+		return errNotSent
+	case <-p.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// Resize the Pool by scaling up or down to accommodate a new size
+func (p *Pool[T]) ScaleTo(newSize int) error {
+	size := p.Size()
+	if newSize < size {
+		return p.ScaleDown(newSize)
+	}
+	if newSize > size {
+		return p.ScaleUp(newSize)
+	}
+	return errors.New("newSize must not be equal to the current size")
+}
+
+// Scale the Pool up to a new specified size
+//
+// Safe to run in the background.
+func (p *Pool[T]) ScaleUp(newSize int) error {
+	p.sizeMutex.Lock()
+	if newSize <= p.size {
+		p.sizeMutex.Unlock()
+		return errors.New("the new size must be greater than the current size")
+	}
+	delta := newSize - p.size
+	p.size = newSize
+	p.sizeMutex.Unlock()
+
+	p.createWorkers(delta)
+	return nil
+}
+
+// Scale the Pool down to a new specified size
+//
+// Blocks until all workers have been stopped.
+// Safe to run in the background.
+func (p *Pool[T]) ScaleDown(newSize int) error {
+	p.sizeMutex.Lock()
+	if newSize < 0 || newSize >= p.size {
+		p.sizeMutex.Unlock()
+		return errors.New("the new size must be between zero and the current size")
+	}
+	delta := p.size - newSize
+	p.size = newSize
+	p.sizeMutex.Unlock()
+
+	p.modClose(delta)
+	for i := 0; i < delta; i++ {
+		select {
+		case p.stop <- struct{}{}:
+			p.modClose(-1)
+		case <-p.ctx.Done():
+			// the remaining workers stop with the pool
+			p.modClose(i - delta)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Stop the Pool by closing the job channel and stopping all workers
+//
+// Calling Stop again, or after StopAndCount, does nothing.
+func (p *Pool[T]) Stop() {
+	p.cancel()
+	p.close()
+}
+
+// Close the job channel once no submissions are in progress, unless
+// it has already been closed
+func (p *Pool[T]) close() {
+	p.closeMutex.Lock()
+	defer p.closeMutex.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.jobs)
+}
+
+// Stop the Pool and keep track of the channels waiting to close
+// by sending a closing signal to each worker. Slower than Stop()
+//
+// Blocks until all workers that were running have been closed.
+func (p *Pool[T]) StopAndCount() {
+	_ = p.ScaleDown(0)
+	p.cancel()
+	p.close()
+}
+
+// Get the total number of workers in this Pool
+func (p *Pool[T]) Size() int {
+	p.sizeMutex.Lock()
+	defer p.sizeMutex.Unlock()
+	return p.size
+}
+
+// Get the number of busy workers in this Pool
+func (p *Pool[T]) Busy() int {
+	p.busyMutex.Lock()
+	defer p.busyMutex.Unlock()
+	return p.busy
+}
+
+// Get the number of workers currently waiting for jobs
+//
+// Equivalent to Size() - Busy()
+func (p *Pool[T]) Waiting() int {
+	return p.Size() - p.Busy() // mutex methods
+}
+
+// Get the number of workers waiting to close in this Pool
+func (p *Pool[T]) Excess() int {
+	p.closingMutex.Lock()
+	defer p.closingMutex.Unlock()
+	return p.closing
+}
+
+func (p *Pool[T]) createWorkers(count int) {
+	for i := 0; i < count; i++ {
+		go func() {
+			for {
+				select {
+				case job, ok := <-p.jobs:
+					if !ok {
+						return
+					}
+					p.modBusy(1)
+					p.run(job)
+					p.modBusy(-1)
+				case <-p.stop:
+					return
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (p *Pool[T]) modClose(change int) {
+	p.closingMutex.Lock()
+	p.closing += change
+	p.closingMutex.Unlock()
+}
+
+func (p *Pool[T]) modBusy(change int) {
+	p.busyMutex.Lock()
+	p.busy += change
+	p.busyMutex.Unlock()
+}
@@ -0,0 +1,125 @@
+package typed
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type job struct {
+	value int
+}
+
+func TestNewPool(t *testing.T) {
+	results := make(chan int, 1)
+	pool := NewPool(2, func(j *job) {
+		results <- j.value * 2
+	})
+	defer pool.Stop()
+
+	pool.Run(&job{value: 21})
+	if result := <-results; result != 42 {
+		t.Error("result should be 42, not", result)
+	}
+}
+
+func TestNewBufferedPool(t *testing.T) {
+	pool := NewBufferedPool(0, 2, func(string) {})
+	pool.Run("a")
+	pool.Run("b") // must not block with a free buffer slot
+	pool.Stop()
+}
+
+func TestPool_SubmitBatch(t *testing.T) {
+	pool := NewBufferedPool(0, 3, func(int) {})
+	pool.SubmitBatch([]int{1, 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	n, err := pool.SubmitBatchCtx(ctx, []int{3, 4})
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+	if n != 1 {
+		t.Error("submitted jobs should equal 1, not", n)
+	}
+}
+
+func TestPool_ScaleTo(t *testing.T) {
+	pool := NewPool(10, func(int) {})
+
+	err := pool.ScaleTo(5)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 5 {
+		t.Error("pool size should be 5, not", pool.Size())
+	}
+
+	err = pool.ScaleTo(25)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 25 {
+		t.Error("pool size should be 25, not", pool.Size())
+	}
+
+	err = pool.ScaleTo(25)
+	if err == nil {
+		t.Error("pool must not accept the same value when scaling")
+	}
+}
+
+func TestPool_StopAndCount(t *testing.T) {
+	pool := NewPool(10, func(int) {})
+	pool.StopAndCount() // blocks until done
+
+	if pool.Excess() != 0 {
+		t.Error("closing count should be 0, not", pool.Excess())
+	}
+}
+
+func TestPool_Stop_Twice(t *testing.T) {
+	pool := NewPool(10, func(int) {})
+	pool.Stop()
+	pool.Stop() // must not panic
+
+	pool = NewPool(10, func(int) {})
+	pool.StopAndCount()
+	pool.Stop()
+}
+
+func TestPool_ScaleDown_Stop(t *testing.T) {
+	pool := NewPool(2, func(int) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	pool.Run(1)
+	pool.Run(2)
+
+	done := make(chan struct{})
+	go func() {
+		// both workers are busy, so this waits until the pool stops
+		_ = pool.ScaleDown(0)
+		close(done)
+	}()
+	<-time.After(time.Millisecond)
+	pool.Stop() // must not panic
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("ScaleDown should return once the pool is stopped")
+	}
+}
+
+func TestPool_Run_Closed(t *testing.T) {
+	pool := NewPool(1, func(int) {})
+	pool.Stop()
+
+	defer func() {
+		if recover() != ErrClosed {
+			t.Error("Run should panic with", ErrClosed, "after the pool is stopped")
+		}
+	}()
+	pool.Run(1)
+}
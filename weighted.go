@@ -0,0 +1,174 @@
+package workers
+
+import "sync"
+
+type WeightedPool struct {
+	// The run function
+	run RunFunc
+
+	// The queued jobs, in submission order
+	queue []*weightedJob
+
+	// The total units of capacity, the units held by running jobs,
+	// and whether the pool has been stopped
+	capacity int
+	used     int
+	stopped  bool
+
+	// The number of jobs which panicked, and the function called when
+	// one does
+	panicked uint64
+	onPanic  func(recovered interface{}, job []interface{})
+
+	// Guards every field above, and signals the dispatcher when jobs
+	// are queued or capacity is released
+	mutex sync.Mutex
+	cond  *sync.Cond
+}
+
+// Create a new WeightedPool with a total capacity shared by its jobs
+//
+// Rather than one job per worker, each job holds a number of units of
+// capacity while it runs, so a heavy job can exclude several light ones.
+// Jobs start in the order they were submitted: a job waits until enough
+// capacity is free, and jobs behind it wait too, so heavy jobs are never
+// starved. Jobs are queued without limit, so submitting never blocks.
+//
+// Panics when capacity < 1
+func NewWeightedPool(capacity int, run RunFunc) *WeightedPool {
+	if capacity < 1 {
+		panic("capacity must be at least one")
+	}
+	pool := &WeightedPool{
+		run:      run,
+		capacity: capacity,
+	}
+	pool.cond = sync.NewCond(&pool.mutex)
+	go pool.dispatch()
+	return pool
+}
+
+// Add a job to this WeightedPool with a weight of one
+func (p *WeightedPool) Run(data ...interface{}) {
+	p.RunW(1, data...)
+}
+
+// Add a job to this WeightedPool which holds weight units of capacity
+// while it runs
+//
+// Jobs submitted after the pool is stopped are discarded.
+//
+// Panics when weight < 1 or weight is greater than the pool's capacity
+func (p *WeightedPool) RunW(weight int, data ...interface{}) {
+	if weight < 1 || weight > p.capacity {
+		panic("weight must be between one and the pool's capacity")
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stopped {
+		return
+	}
+	p.queue = append(p.queue, &weightedJob{weight: weight, args: data})
+	p.cond.Broadcast()
+}
+
+// Stop the WeightedPool, discarding any queued jobs
+//
+// Running jobs are not interrupted.
+func (p *WeightedPool) Stop() {
+	p.mutex.Lock()
+	p.stopped = true
+	p.queue = nil
+	p.mutex.Unlock()
+	p.cond.Broadcast()
+}
+
+// Get the total units of capacity in this WeightedPool
+func (p *WeightedPool) Capacity() int {
+	return p.capacity
+}
+
+// Get the units of capacity held by running jobs
+func (p *WeightedPool) Used() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.used
+}
+
+// Get the number of jobs waiting in the queue
+func (p *WeightedPool) QueueLen() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.queue)
+}
+
+// Start queued jobs in order as capacity becomes free
+func (p *WeightedPool) dispatch() {
+	for {
+		job, ok := p.next()
+		if !ok {
+			return
+		}
+		go p.runJob(job)
+	}
+}
+
+// Set the function called when the run function panics
+//
+// Panics are always recovered so the pool keeps running; the handler
+// receives the recovered value and the job's arguments.
+func (p *WeightedPool) OnPanic(handler func(recovered interface{}, job []interface{})) {
+	p.mutex.Lock()
+	p.onPanic = handler
+	p.mutex.Unlock()
+}
+
+// Get the number of jobs which panicked
+func (p *WeightedPool) Panicked() uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.panicked
+}
+
+// Wait until the next job fits in the free capacity and take it, or
+// return false if the pool has been stopped
+func (p *WeightedPool) next() (*weightedJob, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for !p.stopped && (len(p.queue) == 0 || p.queue[0].weight > p.capacity-p.used) {
+		p.cond.Wait()
+	}
+	if p.stopped {
+		return nil, false
+	}
+	job := p.queue[0]
+	p.queue[0] = nil
+	p.queue = p.queue[1:]
+	p.used += job.weight
+	return job, true
+}
+
+func (p *WeightedPool) runJob(job *weightedJob) {
+	defer func() {
+		// release the capacity even if the job panics
+		recovered := recover()
+		p.mutex.Lock()
+		p.used -= job.weight
+		onPanic := p.onPanic
+		if recovered != nil {
+			p.panicked++
+		}
+		p.mutex.Unlock()
+		p.cond.Broadcast()
+		if recovered != nil && onPanic != nil {
+			onPanic(recovered, job.args)
+		}
+	}()
+	p.run(job.args...)
+}
+
+// A queued job in a WeightedPool
+type weightedJob struct {
+	weight int
+	args   []interface{}
+}
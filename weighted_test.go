@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewWeightedPool(t *testing.T) {
+	var mutex sync.Mutex
+	var running, peak int
+	var wg sync.WaitGroup
+	pool := NewWeightedPool(4, func(data ...interface{}) {
+		weight := data[0].(int)
+		mutex.Lock()
+		running += weight
+		if running > peak {
+			peak = running
+		}
+		mutex.Unlock()
+
+		<-time.After(time.Millisecond)
+
+		mutex.Lock()
+		running -= weight
+		mutex.Unlock()
+		wg.Done()
+	})
+	defer pool.Stop()
+
+	weights := []int{3, 1, 4, 2, 2, 1, 1}
+	wg.Add(len(weights))
+	for _, weight := range weights {
+		pool.RunW(weight, weight)
+	}
+	wg.Wait()
+
+	if peak > 4 {
+		t.Error("running weight should never exceed the capacity of 4, but reached", peak)
+	}
+}
+
+func TestWeightedPool_OnPanic(t *testing.T) {
+	pool := NewWeightedPool(2, func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	})
+	defer pool.Stop()
+
+	recovered := make(chan interface{}, 1)
+	pool.OnPanic(func(r interface{}, job []interface{}) {
+		recovered <- r
+	})
+
+	pool.RunW(2, "panic")
+	if r := <-recovered; r != "bad job" {
+		t.Error("recovered value should be \"bad job\", not", r)
+	}
+	if pool.Panicked() != 1 {
+		t.Error("panicked jobs should equal 1, not", pool.Panicked())
+	}
+}
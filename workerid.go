@@ -0,0 +1,27 @@
+package workers
+
+import "context"
+
+type RunFuncID func(workerID int, data ...interface{})
+
+// Create a new WorkerPool with an initial worker count whose run
+// function receives the id of the worker running each job
+//
+// Each worker holds a stable id for as long as it runs, starting from
+// zero. When workers stop, for example after scaling down, their ids are
+// freed, and new workers take the lowest free ids. Scaling down waits
+// for the stopped workers to exit, so the ids in use stay between zero
+// and the number of workers, and can be used as indexes into a
+// fixed-size slice of per-worker resources. The exception is workers
+// retired by WithIdleTimeout, which give up their ids a moment after
+// leaving the pool's size.
+//
+// Panics when size < 0
+func NewPoolWithID(size int, run RunFuncID) *WorkerPool {
+	pool := newPool(func(_ context.Context, workerID int, data ...interface{}) (interface{}, error) {
+		run(workerID, data...)
+		return nil, nil
+	}, options{size: size})
+	pool.fixedRun = "NewPoolWithID"
+	return pool
+}
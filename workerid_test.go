@@ -0,0 +1,45 @@
+package workers
+
+import "testing"
+
+func TestNewPoolWithID(t *testing.T) {
+	ids := make(chan int, 2)
+	release := make(chan bool)
+	pool := NewPoolWithID(2, func(workerID int, data ...interface{}) {
+		ids <- workerID
+		<-release
+	})
+	defer pool.Stop()
+
+	pool.Run(1)
+	pool.Run(2)
+	first, second := <-ids, <-ids
+	close(release)
+	if first == second {
+		t.Error("jobs running at the same time should have different worker ids, not", first, "and", second)
+	}
+	for _, id := range []int{first, second} {
+		if id < 0 || id >= 2 {
+			t.Error("worker id should be between 0 and 2, not", id)
+		}
+	}
+}
+
+func TestNewPoolWithID_ScaleDownIdle(t *testing.T) {
+	pool := NewPoolWithID(4, func(int, ...interface{}) {})
+	defer pool.Stop()
+
+	for i := 0; i < 10; i++ {
+		if _, err := pool.ScaleDownIdle(1); err != nil {
+			t.Fatal("Error should be nil, not", err.Error())
+		}
+		if err := pool.ScaleUp(4); err != nil {
+			t.Fatal("Error should be nil, not", err.Error())
+		}
+		for _, state := range pool.WorkerStates() {
+			if state.ID >= 4 {
+				t.Fatal("worker id should be between 0 and 4, not", state.ID)
+			}
+		}
+	}
+}
@@ -1,52 +1,227 @@
 package workers
 
 import (
+	"context"
 	"errors"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type RunFunc func(...interface{})
 
+// The run function every pool uses internally, which the public
+// run function types are adapted to
+type runFunc func(ctx context.Context, workerID int, data ...interface{}) (interface{}, error)
+
+// Returned by SubmitWithTimeout when no worker accepts the job in time
+var ErrSubmitTimeout = errors.New("timed out waiting to submit the job")
+
+// Returned when submitting a job to a stopped pool with PolicyError, and
+// the value of the panic with PolicyPanic (see WithClosedPolicy)
+var ErrClosed = errors.New("the pool has been stopped")
+
+var errNotSent = errors.New("the job was not accepted")
+
+// A job's context and arguments, along with the handler
+// generation it was submitted under
+type job struct {
+	ctx  context.Context
+	args []interface{}
+	gen  *generation
+
+	// The number of times this job has already been run and failed,
+	// and when it was first run
+	attempts int
+	firstRun time.Time
+
+	// The future to complete once the job has finished, if any
+	future *Future
+
+	// The channel closed once a worker starts the job, if any
+	started chan struct{}
+
+	// The function called once the job has finished or been discarded,
+	// if any
+	release func()
+}
+
 type WorkerPool struct {
-	// The worker's run function
-	run RunFunc
+	// The number of jobs that have finished running, the number of those
+	// which overran the job timeout or panicked, the number of retries,
+	// and the total time spent running jobs in nanoseconds, updated
+	// atomically. Kept first so that they are 64-bit aligned on 32-bit
+	// platforms
+	processed uint64
+	timedOut  uint64
+	panicked  uint64
+	retried   uint64
+	runTime   uint64
+
+	// The name this pool was registered with, if any
+	name string
+
+	// The constructor of a pool whose run function is not a plain
+	// RunFunc, which SetRunFunc and ReloadHandler refuse to replace
+	fixedRun string
+
+	// The handler generation new jobs are submitted with
+	gen      *generation
+	genMutex sync.Mutex
 
-	// The channel for workers to listen for jobs
-	jobs chan []interface{}
+	// The number of submitted jobs which have not finished, and a
+	// channel which is closed and replaced every time it reaches zero,
+	// both guarded by genMutex
+	pending int
+	idle    chan struct{}
 
-	// The channel to stop a certain number of workers
-	stop chan struct{}
+	// The channel for workers to listen for jobs, whether new jobs are
+	// refused, and whether the channel has been closed, which happens
+	// later when draining. Workers never take closeMutex
+	jobs       chan job
+	closed     bool
+	jobsClosed bool
+	closeMutex sync.RWMutex
+
+	// The submissions which are sending on the job channel, which must
+	// finish before it is closed
+	sending sync.WaitGroup
+
+	// The channel for workers to send results on, if any
+	results chan interface{}
+
+	// The channel for workers to send job errors on, if any, and what
+	// to drop when it is full
+	errs       chan error
+	dropPolicy DropPolicy
+	errsMutex  sync.Mutex
 
 	// The size of this worker pool (number of workers)
 	size      int
 	sizeMutex sync.Mutex
 
-	// The number of busy workers in this worker pool
-	busy      int
-	busyMutex sync.Mutex
+	// Held for the whole of ScaleTo, ScaleUp, ScaleDown and
+	// ScaleDownIdle, including creating and stopping workers, so
+	// concurrent calls cannot work from a stale size
+	scaleMutex sync.Mutex
+
+	// The channel completed scale operations are reported on
+	scaleEvents chan ScaleEvent
+
+	// Whether workers are paused, a channel closed to pause them, and
+	// a channel closed to resume them. Both are replaced after closing
+	paused     bool
+	pause      chan struct{}
+	resume     chan struct{}
+	pauseMutex sync.Mutex
+
+	// How long a worker waits for a job before stopping itself, and
+	// the number of workers which never stop for being idle
+	idleTimeout time.Duration
+	minWorkers  int
 
-	// The number of workers waiting to close
+	// How long each job may run before its context is cancelled
+	jobTimeout time.Duration
+
+	// How many times a failing job is run, how long to wait before
+	// each retry, and how long after its first run a job may be retried
+	maxAttempts   int
+	backoff       func(attempt int) time.Duration
+	retryDeadline time.Duration
+
+	// The futures of jobs waiting in the job buffer or for a retry, which
+	// fail with ErrClosed if the pool is stopped before a worker runs them
+	waitingFutures      map[*Future]struct{}
+	waitingFuturesMutex sync.Mutex
+
+	// The function called with jobs which fail for the last time, if any
+	deadLetter func(data []interface{}, lastErr error, attempts int)
+
+	// The number of busy workers in this worker pool, the highest number
+	// seen, and a channel which is closed and replaced every time the
+	// number changes
+	busy        int
+	peakBusy    int
+	busyChanged chan struct{}
+	busyMutex   sync.Mutex
+
+	// The number of workers waiting to close, the scale-down batches
+	// they belong to (oldest first), and a context which is cancelled
+	// and replaced every time a batch is added, waking idle workers
 	closing      int
+	stopBatches  []*stopBatch
+	stopCtx      context.Context
+	stopCancel   context.CancelFunc
 	closingMutex sync.Mutex
+
+	// The highest number of jobs observed in the job buffer
+	peakQueue      int
+	peakQueueMutex sync.Mutex
+
+	// The number of workers to create before yielding when scaling up
+	spawnBatch      int
+	spawnBatchMutex sync.Mutex
+
+	// The function called when a job panics
+	onPanic      func(recovered interface{}, job []interface{})
+	onPanicMutex sync.Mutex
+
+	// The number of workers detached to run exclusive tasks
+	detached      int
+	detachedMutex sync.Mutex
+
+	// The worker goroutines which are still running
+	workers sync.WaitGroup
+
+	// Which worker ids are held by running workers, and when the job
+	// each worker is running started, or the zero time if it is idle
+	workerIDs      []bool
+	jobStarts      []time.Time
+	workerIDsMutex sync.Mutex
+
+	// The functions called when each worker starts and stops
+	onWorkerStart func(id int)
+	onWorkerStop  func(id int)
+
+	// The function called on lifecycle events, if any
+	logger LogFunc
+
+	// The function called around every job, if any
+	jobHook JobHook
+
+	// The weight given to each new job duration in the moving average,
+	// or zero for a plain average, and the moving average so far
+	ewmaAlpha float64
+	ewma      time.Duration
+	ewmaMutex sync.Mutex
+
+	// The token bucket limiting how often jobs start, if any
+	limiter *limiter
+
+	// The function called after every job, if any
+	onComplete func(data []interface{}, dur time.Duration, recovered interface{})
+
+	// What happens to jobs submitted after the pool is stopped
+	closedPolicy ClosedPolicy
+
+	// How often the health check runs, the check itself, and whether
+	// it passed last time
+	healthInterval time.Duration
+	healthCheck    func() bool
+	healthy        bool
+	healthyMutex   sync.Mutex
+
+	// The context cancelled when this pool is stopped
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // Create a new WorkerPool with an initial worker count
 //
 // Panics when size < 0
 func NewPool(size int, run RunFunc) *WorkerPool {
-	if size < 0 {
-		panic("size must be greater than zero")
-	}
-	pool := &WorkerPool{
-		run:  run,
-		jobs: make(chan []interface{}),
-		stop: make(chan struct{}),
-		size: size,
-		busy: 0,
-	}
-	// spawn workers up to the limit
-	pool.createWorkers(size)
-	return pool
+	return NewBufferedPool(size, 0, run)
 }
 
 // Create a new WorkerPool with an initial worker count and job buffer size
@@ -56,80 +231,562 @@ func NewPool(size int, run RunFunc) *WorkerPool {
 //
 // Panics when size < 0
 func NewBufferedPool(size, bufSize int, run RunFunc) *WorkerPool {
-	if size < 0 {
+	return newPool(ignoreContext(run), options{size: size, bufSize: bufSize})
+}
+
+func newPool(run runFunc, opts options) *WorkerPool {
+	if opts.size < 0 {
 		panic("size must be greater than zero")
 	}
+	ctx, cancel := context.WithCancel(context.Background())
 	pool := &WorkerPool{
-		run:  run,
-		jobs: make(chan []interface{}, bufSize),
-		stop: make(chan struct{}),
-		size: size,
-		busy: 0,
+		name:           opts.name,
+		gen:            newGeneration(run),
+		idle:           make(chan struct{}),
+		jobs:           make(chan job, opts.bufSize),
+		size:           opts.size,
+		idleTimeout:    opts.idleTimeout,
+		minWorkers:     opts.minWorkers,
+		jobTimeout:     opts.jobTimeout,
+		maxAttempts:    opts.maxAttempts,
+		backoff:        opts.backoff,
+		retryDeadline:  opts.retryDeadline,
+		deadLetter:     opts.deadLetter,
+		busy:           0,
+		busyChanged:    make(chan struct{}),
+		pause:          make(chan struct{}),
+		resume:         make(chan struct{}),
+		onPanic:        opts.onPanic,
+		onWorkerStart:  opts.onWorkerStart,
+		onWorkerStop:   opts.onWorkerStop,
+		logger:         opts.logger,
+		jobHook:        opts.jobHook,
+		ewmaAlpha:      opts.ewmaAlpha,
+		onComplete:     opts.onComplete,
+		closedPolicy:   opts.closedPolicy,
+		scaleEvents:    make(chan ScaleEvent, scaleEventBuffer),
+		healthInterval: opts.healthInterval,
+		healthCheck:    opts.healthCheck,
+		healthy:        true,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	pool.stopCtx, pool.stopCancel = context.WithCancel(context.Background())
+	if opts.rateLimit > 0 {
+		pool.limiter = newLimiter(opts.rateLimit, opts.burst)
 	}
 	// spawn workers up to the limit
-	pool.createWorkers(size)
+	pool.createWorkers(opts.size)
+	pool.startHealthCheck(ctx)
 	return pool
 }
 
 // Add a job to this WorkerPool
-func (w *WorkerPool) Run(data ...interface{}) {
-	w.jobs <- data
+//
+// When called with an existing slice (Run(args...)), the worker receives
+// that same slice, so changes the caller makes to it afterward are seen
+// by the worker. Use SafeRun if the slice may be reused.
+//
+// If the pool has been stopped, Run follows the pool's closed policy. By
+// default it still panics, for compatibility; pass
+// WithClosedPolicy(PolicyError) to get ErrClosed back instead. Code which
+// relies on the panic should move to MustRun, since PolicyError may
+// become the default in the future.
+func (w *WorkerPool) Run(data ...interface{}) error {
+	return w.enqueue(data)
+}
+
+// Add a job to this WorkerPool and wait until a worker starts running
+// it, unlike Run, which returns once the job is queued
+//
+// Returns ErrClosed without waiting any longer if the pool is stopped
+// before the job starts. Otherwise follows the pool's closed policy
+// like Run.
+func (w *WorkerPool) RunSync(data ...interface{}) error {
+	done := w.Done()
+	job := w.newJob(context.Background(), data)
+	job.started = make(chan struct{})
+	if err := w.send(job, nil, true); err != nil {
+		return w.onClosed()
+	}
+
+	select {
+	case <-job.started:
+		return nil
+	case <-done:
+		return ErrClosed
+	}
+}
+
+// Add a job to this WorkerPool, panicking with ErrClosed if the pool has
+// been stopped whatever its closed policy
+func (w *WorkerPool) MustRun(data ...interface{}) {
+	if err := w.send(w.newJob(context.Background(), data), nil, true); err != nil {
+		panic(err)
+	}
+}
+
+// Add a copy of a job's arguments to this WorkerPool, so the worker
+// always sees a stable snapshot even if the caller reuses the slice
+//
+// Follows the pool's closed policy like Run.
+func (w *WorkerPool) SafeRun(data ...interface{}) error {
+	args := make([]interface{}, len(data))
+	copy(args, data)
+	return w.enqueue(args)
+}
+
+// Add a job to this WorkerPool only if it can be accepted immediately
+//
+// Returns false without blocking if the job buffer is full, or for an
+// unbuffered pool, if no worker is ready to receive the job right now.
+func (w *WorkerPool) TrySubmit(data ...interface{}) bool {
+	return w.tryEnqueue(data)
+}
+
+// Add a job to this WorkerPool, waiting at most d for it to be accepted
+//
+// Returns ErrSubmitTimeout if the job could not be queued in time.
+func (w *WorkerPool) SubmitWithTimeout(d time.Duration, data ...interface{}) error {
+	timeout, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel() // stops the timer when the job is accepted early
+
+	err := w.send(w.newJob(context.Background(), data), timeout.Done(), true)
+	if err == errNotSent {
+		return ErrSubmitTimeout
+	}
+	if err != nil {
+		return w.onClosed()
+	}
+	return nil
+}
+
+// Add a job to this WorkerPool, waiting until it is accepted or the
+// context is cancelled
+//
+// Returns the context's error if it is cancelled first. Unlike RunCtx,
+// the context only bounds the wait: once accepted, the job runs even if
+// the context is cancelled before a worker starts it.
+func (w *WorkerPool) RunWaitCtx(ctx context.Context, data ...interface{}) error {
+	err := w.send(w.newJob(context.Background(), data), ctx.Done(), true)
+	if err == errNotSent {
+		return ctx.Err()
+	}
+	if err != nil {
+		return w.onClosed()
+	}
+	return nil
+}
+
+// Add a job to this WorkerPool once a delay has passed
+//
+// No worker is used while waiting. If the pool is stopped before the
+// delay has passed, the job is discarded.
+func (w *WorkerPool) RunAfter(d time.Duration, data ...interface{}) {
+	done := w.Done()
+	go func() {
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+			// the job is dropped if the pool stops at the same time
+			_ = w.send(w.newJob(context.Background(), data), nil, true)
+		case <-done:
+			timer.Stop()
+		}
+	}()
+}
+
+// Add a job to this WorkerPool at a specific time
+//
+// Equivalent to RunAfter(time.Until(t), data...)
+func (w *WorkerPool) RunAt(t time.Time, data ...interface{}) {
+	w.RunAfter(time.Until(t), data...)
+}
+
+// Add many jobs to this WorkerPool, stopping early if the context is
+// cancelled while waiting to submit one
+//
+// Returns the number of jobs submitted, and the context's error if the
+// batch was interrupted. Like RunWaitCtx, the context only bounds the
+// wait: every job counted as submitted runs even if the context is
+// cancelled before a worker starts it.
+func (w *WorkerPool) RunBatchCtx(ctx context.Context, jobs [][]interface{}) (int, error) {
+	for i, job := range jobs {
+		if err := w.RunWaitCtx(ctx, job...); err != nil {
+			return i, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// Add many jobs to this WorkerPool, in order
+//
+// Blocks like Run when the job buffer is full.
+func (w *WorkerPool) SubmitBatch(jobs [][]interface{}) {
+	for _, job := range jobs {
+		if err := w.enqueue(job); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Add many jobs to this WorkerPool, stopping early if the context is
+// cancelled while waiting to submit one
+//
+// Equivalent to RunBatchCtx(ctx, jobs)
+func (w *WorkerPool) SubmitBatchCtx(ctx context.Context, jobs [][]interface{}) (int, error) {
+	return w.RunBatchCtx(ctx, jobs)
+}
+
+// Remove and return all of the jobs currently queued in the WorkerPool's
+// job buffer, so they can be persisted and restored later with LoadQueue
+//
+// Only queued jobs are captured; jobs that workers have already picked
+// up are not. Running workers compete for the same buffer, so scale the
+// pool down to zero first to capture the complete backlog.
+func (w *WorkerPool) SnapshotQueue() [][]interface{} {
+	_, queue := w.channels()
+	var jobs [][]interface{}
+	for {
+		select {
+		case job, ok := <-queue:
+			if !ok {
+				// the pool was stopped and the buffer is empty
+				return jobs
+			}
+			w.discard(job)
+			jobs = append(jobs, job.args)
+		default:
+			return jobs
+		}
+	}
+}
+
+// Add previously snapshotted jobs to this WorkerPool, in order
+//
+// Blocks like Run when the job buffer is full. The pool only moves the
+// argument slices around; serializing them is up to the caller.
+func (w *WorkerPool) LoadQueue(jobs [][]interface{}) {
+	for _, job := range jobs {
+		if err := w.enqueue(job); err != nil {
+			panic(err)
+		}
+	}
 }
 
 // Resize the WorkerPool by scaling up or down to accommodate a new size
 func (w *WorkerPool) ScaleTo(newSize int) error {
-	if newSize < w.size {
-		return w.ScaleDown(newSize)
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	size := w.Size()
+	if newSize < size {
+		return w.scaleDown(newSize)
 	}
-	if newSize > w.size {
-		return w.ScaleUp(newSize)
+	if newSize > size {
+		return w.scaleUp(newSize)
 	}
 	return errors.New("newSize must not be equal to the current size")
 }
 
+// Resize the WorkerPool like ScaleTo, but succeed without doing anything
+// when newSize is already the current size
+//
+// Returns an error when newSize < 0.
+func (w *WorkerPool) Resize(newSize int) error {
+	if newSize < 0 {
+		return errors.New("the new size must not be negative")
+	}
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	size := w.Size()
+	if newSize < size {
+		return w.scaleDown(newSize)
+	}
+	if newSize > size {
+		return w.scaleUp(newSize)
+	}
+	return nil
+}
+
 // Scale the WorkerPool up to a new specified size
 //
 // Safe to run in the background.
 func (w *WorkerPool) ScaleUp(newSize int) error {
-	if newSize <= w.size {
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	return w.scaleUp(newSize)
+}
+
+// Scale the WorkerPool down to a new specified size
+//
+// Blocks until all workers have been stopped, and other scaling calls
+// wait for it to finish.
+// Safe to run in the background.
+func (w *WorkerPool) ScaleDown(newSize int) error {
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	return w.scaleDown(newSize)
+}
+
+// Scale the WorkerPool up by delta workers
+//
+// The current size is read and changed under the same lock, so
+// concurrent relative adjustments never work from a stale size.
+func (w *WorkerPool) ScaleUpBy(delta int) error {
+	if delta < 1 {
+		return errors.New("delta must be greater than zero")
+	}
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	return w.scaleUp(w.Size() + delta)
+}
+
+// Scale the WorkerPool down by delta workers
+//
+// Blocks until the workers have been stopped, like ScaleDown. Returns an
+// error if the pool has fewer than delta workers.
+func (w *WorkerPool) ScaleDownBy(delta int) error {
+	if delta < 1 {
+		return errors.New("delta must be greater than zero")
+	}
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
+	return w.scaleDown(w.Size() - delta)
+}
+
+// must be called with scaleMutex held
+func (w *WorkerPool) scaleUp(newSize int) error {
+	w.sizeMutex.Lock()
+	oldSize := w.size
+	if newSize <= oldSize {
+		w.sizeMutex.Unlock()
 		return errors.New("the new size must be greater than the current size")
 	}
+	w.size = newSize
+	w.sizeMutex.Unlock()
+
+	if w.logger != nil {
+		w.logger("scale_up", map[string]interface{}{"from": oldSize, "to": newSize})
+	}
+	w.createWorkers(newSize - oldSize)
+	w.emitScale(oldSize, newSize)
+	return nil
+}
 
+// must be called with scaleMutex held
+func (w *WorkerPool) scaleDown(newSize int) error {
 	w.sizeMutex.Lock()
-	delta := newSize - w.size
+	oldSize := w.size
+	if newSize < 0 || newSize >= oldSize {
+		w.sizeMutex.Unlock()
+		return errors.New("the new size must be between zero and the current size")
+	}
 	w.size = newSize
 	w.sizeMutex.Unlock()
 
-	w.createWorkers(delta)
+	if w.logger != nil {
+		w.logger("scale_down", map[string]interface{}{"from": oldSize, "to": newSize})
+	}
+	w.stopWorkers(oldSize - newSize)
+	w.emitScale(oldSize, newSize)
 	return nil
 }
 
-// Scale the WorkerPool down to a new specified size
+// Set the function called when the run function panics
 //
-// Blocks until all workers have been stopped.
-// Safe to run in the background.
-func (w *WorkerPool) ScaleDown(newSize int) error {
-	if newSize < 0 || newSize >= w.size {
-		return errors.New("the new size must be between zero and the current size")
+// Panics are always recovered so the worker keeps running; the
+// handler receives the recovered value and the job's arguments.
+func (w *WorkerPool) OnPanic(handler func(recovered interface{}, job []interface{})) {
+	w.onPanicMutex.Lock()
+	w.onPanic = handler
+	w.onPanicMutex.Unlock()
+}
+
+// Stop workers from taking new jobs until Resume is called
+//
+// Jobs which are already running are finished, and queued jobs are
+// kept. Submitting blocks once the job buffer is full.
+func (w *WorkerPool) Pause() {
+	w.pauseMutex.Lock()
+	defer w.pauseMutex.Unlock()
+	if w.paused {
+		return
 	}
+	w.paused = true
+	close(w.pause)
+	w.pause = make(chan struct{})
+}
+
+// Let workers take new jobs again after Pause
+func (w *WorkerPool) Resume() {
+	w.pauseMutex.Lock()
+	defer w.pauseMutex.Unlock()
+	if !w.paused {
+		return
+	}
+	w.paused = false
+	close(w.resume)
+	w.resume = make(chan struct{})
+}
+
+// Get whether this WorkerPool is paused
+func (w *WorkerPool) Paused() bool {
+	w.pauseMutex.Lock()
+	defer w.pauseMutex.Unlock()
+	return w.paused
+}
+
+// Take one worker out of the WorkerPool to run a long-lived task
+//
+// Blocks until a worker is free, then runs fn in the background. Other
+// workers keep taking jobs with one fewer worker available until fn
+// returns, at which point a replacement worker is created. Returns
+// without running fn if the pool is stopped while waiting.
+func (w *WorkerPool) Detach(fn func()) {
+	if w.stopWorkers(1) < 1 {
+		return
+	}
+	w.modDetached(1)
+	go func() {
+		defer func() {
+			// Restart reads both together to leave the slot to us
+			w.detachedMutex.Lock()
+			w.createWorkers(1)
+			w.detached--
+			w.detachedMutex.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// Replace every worker in the WorkerPool with a fresh one, keeping the
+// same size and leaving queued jobs in place for the new workers
+//
+// Blocks until the old workers have finished their current jobs and
+// stopped. Detached workers are replaced when their task returns.
+func (w *WorkerPool) RecycleWorkers() {
+	count := w.Size() - w.Detached()
+	if w.stopWorkers(count) < count {
+		return
+	}
+	w.createWorkers(count)
+}
+
+// Scale the WorkerPool down toward a new specified size by stopping only
+// workers that are currently waiting for jobs
+//
+// Workers count as busy from the moment they take a job, including
+// while waiting for their turn under WithRateLimit. Waits for the idle
+// workers to exit, which they do right away, so that their ids are free
+// before the next scale-up. Returns the size actually reached, which is
+// greater than newSize when too many workers were busy.
+func (w *WorkerPool) ScaleDownIdle(newSize int) (int, error) {
+	w.scaleMutex.Lock()
+	defer w.scaleMutex.Unlock()
 
 	w.sizeMutex.Lock()
-	delta := w.size - newSize
-	w.size = newSize
+	if newSize < 0 || newSize >= w.size {
+		w.sizeMutex.Unlock()
+		return w.Size(), errors.New("the new size must be between zero and the current size")
+	}
+	idle := w.size - w.Busy() - w.Detached() - w.Excess()
+	if idle > w.size-newSize {
+		idle = w.size - newSize
+	}
+	if idle <= 0 {
+		w.sizeMutex.Unlock()
+		return w.Size(), nil
+	}
+	oldSize := w.size
+	w.size -= idle
+	size := w.size
 	w.sizeMutex.Unlock()
 
-	w.modClose(delta)
-	for i := 0; i < delta; i++ {
-		w.stop <- struct{}{}
-		w.modClose(-1)
+	if w.logger != nil {
+		w.logger("scale_down", map[string]interface{}{"from": oldSize, "to": size})
 	}
-	return nil
+	// wait so that the stopped workers' ids are free before the next
+	// scale-up, and new workers cannot claim the batch in their place
+	w.awaitStop(w.postStop(idle))
+	w.emitScale(oldSize, size)
+	return size, nil
+}
+
+// Set the number of workers created at a time when scaling up, yielding
+// to other goroutines between batches
+//
+// Pacing very large scale-ups keeps a single call from starving the rest
+// of the process. Size() still reports the new size immediately. A batch
+// size of zero (the default) creates all workers at once.
+func (w *WorkerPool) SetSpawnBatchSize(batchSize int) {
+	w.spawnBatchMutex.Lock()
+	w.spawnBatch = batchSize
+	w.spawnBatchMutex.Unlock()
 }
 
-// Stop the WorkerPool by closing all channels and stopping all workers
+// Stop the WorkerPool by closing the job channel and stopping all workers
+//
+// Calling Stop again, or after DrainAndStop or StopAndCount, does nothing.
 func (w *WorkerPool) Stop() {
-	close(w.jobs)
-	close(w.stop)
+	w.halt()
+	w.close()
+	w.deregister()
+}
+
+// Stop accepting new jobs, let the workers finish every job already in
+// the job buffer, and then stop the WorkerPool
+//
+// Blocks until all queued and in-flight jobs have finished, including
+// retries of failed jobs. Unlike Stop, no queued work is discarded.
+func (w *WorkerPool) DrainAndStop() {
+	// failed jobs may still be retried until everything has finished
+	w.refuse()
+	w.Wait()
+	w.close()
+	w.halt()
+	w.deregister()
+}
+
+// Stop the WorkerPool like Stop, then wait up to grace for the jobs which
+// were already running to finish
+//
+// Returns the number of jobs still running when the grace period ended,
+// which is zero if they all finished in time.
+func (w *WorkerPool) StopGraceful(grace time.Duration) (stragglers int) {
+	w.Stop()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	for {
+		w.busyMutex.Lock()
+		busy, changed := w.busy, w.busyChanged
+		w.busyMutex.Unlock()
+		if busy == 0 {
+			return 0
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return w.Busy()
+		}
+	}
+}
+
+// Stop accepting new jobs and stop the WorkerPool once every queued and
+// in-flight job has finished, or as soon as the context is cancelled
+//
+// Like http.Server#Shutdown, returns nil after a clean drain, or the
+// context's error if it was cancelled first, in which case the pool is
+// stopped as with Stop and any jobs still queued are discarded.
+func (w *WorkerPool) Shutdown(ctx context.Context) error {
+	w.refuse()
+	err := w.WaitIdle(ctx)
+	// halt first so that submissions still blocked give up
+	w.halt()
+	w.close()
+	w.deregister()
+	return err
 }
 
 // Stop the WorkerPool and keep track of the channels waiting to close
@@ -139,8 +796,91 @@ func (w *WorkerPool) Stop() {
 // stopped due to down-scaling do not cause this function to block.
 func (w *WorkerPool) StopAndCount() {
 	_ = w.ScaleDown(0)
-	close(w.jobs)
-	close(w.stop)
+	w.halt()
+	w.close()
+	w.deregister()
+}
+
+// Start a stopped WorkerPool again with fresh channels and Size() workers
+// (less any which are still detached)
+//
+// Blocks until the old workers have finished their current jobs. Jobs
+// left in the job buffer when the pool was stopped are discarded. A pool
+// created with NewRegisteredPool is registered again if its name is
+// still free. Does nothing if the pool has not been stopped.
+func (w *WorkerPool) Restart() {
+	w.closeMutex.RLock()
+	closed := w.closed
+	w.closeMutex.RUnlock()
+	if !closed {
+		return
+	}
+
+	w.halt()
+	// in case the pool is still draining
+	w.close()
+	w.workers.Wait()
+	_, queue := w.channels()
+	for job := range queue {
+		w.discard(job)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.closeMutex.Lock()
+	w.ctx, w.cancel = ctx, cancel
+	w.jobs = make(chan job, cap(queue))
+	w.closed = false
+	w.jobsClosed = false
+	w.closeMutex.Unlock()
+
+	w.resetStops()
+	w.register()
+	// detached workers are replaced when their task returns
+	size := w.Size()
+	w.detachedMutex.Lock()
+	w.createWorkers(size - w.detached)
+	w.detachedMutex.Unlock()
+	w.startHealthCheck(ctx)
+}
+
+// Get whether this WorkerPool has stopped accepting jobs, because it
+// has been stopped or is draining with DrainAndStop or Shutdown
+//
+// Jobs submitted to a closed pool are handled by its closed policy (see
+// WithClosedPolicy), which panics by default.
+func (w *WorkerPool) Closed() bool {
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+	return w.closed
+}
+
+// Get a channel which is closed when this WorkerPool is stopped, for
+// use in select statements
+//
+// Equivalent to Context().Done(). After DrainAndStop, it is closed once
+// the queued jobs have finished. A restarted pool has a new channel.
+func (w *WorkerPool) Done() <-chan struct{} {
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+	return w.ctx.Done()
+}
+
+// Get a context that is cancelled when this WorkerPool is stopped
+//
+// Handlers and the goroutines they spawn can derive from it to tie
+// their lifetime to the pool's.
+func (w *WorkerPool) Context() context.Context {
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+	return w.ctx
+}
+
+// Get the pool's context and job channel together, since Restart
+// replaces both
+func (w *WorkerPool) channels() (context.Context, chan job) {
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+	return w.ctx, w.jobs
 }
 
 // Get the total number of workers in this WorkerPool
@@ -157,11 +897,159 @@ func (w *WorkerPool) Busy() int {
 	return w.busy
 }
 
+// Get the number of jobs this WorkerPool has finished running,
+// including jobs which panicked
+func (w *WorkerPool) Processed() uint64 {
+	return atomic.LoadUint64(&w.processed)
+}
+
+// Get the number of jobs which panicked, which are also counted by
+// Processed
+func (w *WorkerPool) Panicked() uint64 {
+	return atomic.LoadUint64(&w.panicked)
+}
+
+// Get the highest number of workers that were busy at the same time
+// since this WorkerPool was created or ResetPeak was called
+func (w *WorkerPool) PeakBusy() int {
+	w.busyMutex.Lock()
+	defer w.busyMutex.Unlock()
+	return w.peakBusy
+}
+
+// Reset the peak number of busy workers to the current number
+func (w *WorkerPool) ResetPeak() {
+	w.busyMutex.Lock()
+	w.peakBusy = w.busy
+	w.busyMutex.Unlock()
+}
+
+// Get the average time the run function took per job, or zero if no
+// jobs have finished
+//
+// With WithDurationEWMA, this is an exponentially weighted moving
+// average, so recent jobs count the most.
+func (w *WorkerPool) AvgDuration() time.Duration {
+	if w.ewmaAlpha > 0 {
+		w.ewmaMutex.Lock()
+		defer w.ewmaMutex.Unlock()
+		return w.ewma
+	}
+
+	processed := atomic.LoadUint64(&w.processed)
+	if processed == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadUint64(&w.runTime) / processed)
+}
+
+// Get the number of jobs which were still running when the job
+// timeout set with WithJobTimeout elapsed
+func (w *WorkerPool) TimedOut() uint64 {
+	return atomic.LoadUint64(&w.timedOut)
+}
+
+// Wait until at least n workers in this WorkerPool are busy
+//
+// Returns the context's error if it is cancelled first.
+func (w *WorkerPool) WaitBusy(n int, ctx context.Context) error {
+	for {
+		w.busyMutex.Lock()
+		busy, changed := w.busy, w.busyChanged
+		w.busyMutex.Unlock()
+		if busy >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Wait until every submitted job has finished, including both queued
+// and in-flight jobs
+//
+// Returns early if the WorkerPool is stopped, since queued jobs may
+// then be discarded.
+func (w *WorkerPool) Wait() {
+	_ = w.WaitIdle(context.Background())
+}
+
+// Wait until every submitted job has finished, like Wait, or until the
+// context is cancelled
+//
+// Returns the context's error if it is cancelled first. Like Wait,
+// returns nil early if the WorkerPool is stopped.
+func (w *WorkerPool) WaitIdle(ctx context.Context) error {
+	w.genMutex.Lock()
+	pending, idle := w.pending, w.idle
+	w.genMutex.Unlock()
+	if pending == 0 {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-w.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait until every job in the job buffer has been taken by a worker,
+// without waiting for the jobs to finish
+//
+// Blocks while the pool is paused. Returns early if the WorkerPool is
+// stopped.
+func (w *WorkerPool) Flush() {
+	done := w.Done()
+	for {
+		// workers mark themselves busy after taking a job
+		w.busyMutex.Lock()
+		changed := w.busyChanged
+		w.busyMutex.Unlock()
+		if w.QueueLen() == 0 {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-done:
+			return
+		}
+	}
+}
+
 // Get the number of workers currently waiting for jobs
 //
-// Equivalent to Size() - Busy()
+// Equivalent to Size() - Busy() - Detached(), read as one consistent
+// snapshot. Never negative, even while busy workers are waiting to be
+// stopped after a scale-down.
 func (w *WorkerPool) Waiting() int {
-	return w.Size() - w.Busy() // mutex methods
+	w.sizeMutex.Lock()
+	w.busyMutex.Lock()
+	w.detachedMutex.Lock()
+	waiting := w.size - w.busy - w.detached
+	w.detachedMutex.Unlock()
+	w.busyMutex.Unlock()
+	w.sizeMutex.Unlock()
+
+	if waiting < 0 {
+		return 0
+	}
+	return waiting
+}
+
+// Get the number of workers detached to run tasks with Detach
+func (w *WorkerPool) Detached() int {
+	w.detachedMutex.Lock()
+	defer w.detachedMutex.Unlock()
+	return w.detached
 }
 
 // Get the number of workers waiting to close in this WorkerPool
@@ -171,40 +1059,594 @@ func (w *WorkerPool) Excess() int {
 	return w.closing
 }
 
+// Get the number of jobs waiting in the job buffer
+func (w *WorkerPool) QueueLen() int {
+	_, queue := w.channels()
+	return len(queue)
+}
+
+// Get the capacity of the job buffer, which is zero for unbuffered pools
+func (w *WorkerPool) QueueCap() int {
+	_, queue := w.channels()
+	return cap(queue)
+}
+
+// Get the highest number of jobs observed waiting in the job buffer
+// since this WorkerPool was created or ResetPeakQueue was called
+func (w *WorkerPool) PeakQueueLength() int {
+	w.peakQueueMutex.Lock()
+	defer w.peakQueueMutex.Unlock()
+	return w.peakQueue
+}
+
+// Reset the peak queue length to the current queue length
+func (w *WorkerPool) ResetPeakQueue() {
+	w.peakQueueMutex.Lock()
+	w.peakQueue = w.QueueLen()
+	w.peakQueueMutex.Unlock()
+}
+
+// Submit a job, handling a stopped pool with the closed policy
+func (w *WorkerPool) enqueue(args []interface{}) error {
+	if err := w.send(w.newJob(context.Background(), args), nil, true); err != nil {
+		return w.onClosed()
+	}
+	return nil
+}
+
+// Submit a job carrying ctx, giving up if ctx is cancelled first
+func (w *WorkerPool) enqueueCtx(ctx context.Context, args []interface{}) error {
+	err := w.send(w.newJob(ctx, args), ctx.Done(), true)
+	if err == errNotSent {
+		return ctx.Err()
+	}
+	if err != nil {
+		return w.onClosed()
+	}
+	return nil
+}
+
+// Submit a job only if it can be accepted without blocking
+func (w *WorkerPool) tryEnqueue(args []interface{}) bool {
+	err := w.send(w.newJob(context.Background(), args), nil, false)
+	if err == ErrClosed {
+		_ = w.onClosed()
+	}
+	return err == nil
+}
+
+// Handle a job submitted after the pool was stopped according to the
+// closed policy: panic, return ErrClosed, or drop the job
+func (w *WorkerPool) onClosed() error {
+	switch w.closedPolicy {
+	case PolicyError:
+		return ErrClosed
+	case PolicyDrop:
+		return nil
+	}
+	panic(ErrClosed)
+}
+
+// Send a job to the workers. When wait is true, blocks until the job is
+// accepted or abort is closed, otherwise gives up if it cannot be
+// accepted immediately. Returns ErrClosed if the pool is stopped first.
+func (w *WorkerPool) send(job job, abort <-chan struct{}, wait bool) error {
+	w.closeMutex.RLock()
+	w.holdFuture(job)
+	// retries of accepted jobs are still queued while draining
+	if w.closed && (job.attempts == 0 || w.jobsClosed) {
+		w.closeMutex.RUnlock()
+		w.discard(job)
+		return ErrClosed
+	}
+	// rather than holding the lock while blocked, which would hold up
+	// close and everything waiting behind it, let close wait for us
+	w.sending.Add(1)
+	defer w.sending.Done()
+	jobs, done := w.jobs, w.ctx.Done()
+	w.closeMutex.RUnlock()
+
+	if wait {
+		select {
+		case jobs <- job:
+		case <-abort:
+			w.discard(job)
+			return errNotSent
+		case <-done:
+			w.discard(job)
+			return ErrClosed
+		}
+	} else {
+		select {
+		case jobs <- job:
+		default:
+			w.discard(job)
+			return errNotSent
+		}
+	}
+	w.observeQueue(len(jobs))
+	return nil
+}
+
+// Cancel the pool's context, failing the futures of jobs which will
+// now never run
+func (w *WorkerPool) halt() {
+	w.closeMutex.RLock()
+	cancel := w.cancel
+	w.closeMutex.RUnlock()
+	cancel()
+	w.failFutures()
+}
+
+// Refuse new jobs and close the job channel once no submissions are in
+// progress, unless it has already been closed
+func (w *WorkerPool) close() {
+	w.closeMutex.Lock()
+	w.closed = true
+	if w.jobsClosed {
+		w.closeMutex.Unlock()
+		return
+	}
+	w.jobsClosed = true
+	jobs := w.jobs
+	w.closeMutex.Unlock()
+
+	// no more sends can start, but some may still be blocked
+	w.sending.Wait()
+	close(jobs)
+}
+
+// Refuse new jobs, but leave the job channel open for retries of jobs
+// which were already accepted
+func (w *WorkerPool) refuse() {
+	w.closeMutex.Lock()
+	w.closed = true
+	w.closeMutex.Unlock()
+}
+
+func (w *WorkerPool) newJob(ctx context.Context, args []interface{}) job {
+	w.genMutex.Lock()
+	gen := w.gen
+	gen.pending++
+	w.pending++
+	w.genMutex.Unlock()
+	return job{ctx: ctx, args: args, gen: gen}
+}
+
+// Drop a job without running it, failing its future with ErrClosed
+func (w *WorkerPool) discard(job job) {
+	if w.takeFuture(job) && job.future != nil {
+		job.future.complete(nil, ErrClosed, nil)
+	}
+	w.finish(job)
+}
+
+// Count a job as done, whether it ran or was discarded
+func (w *WorkerPool) finish(job job) {
+	if job.release != nil {
+		job.release()
+	}
+	w.genMutex.Lock()
+	gen := job.gen
+	gen.pending--
+	if gen.retired && gen.pending == 0 {
+		close(gen.drained)
+	}
+	w.pending--
+	if w.pending == 0 {
+		close(w.idle)
+		w.idle = make(chan struct{})
+	}
+	w.genMutex.Unlock()
+}
+
+func (w *WorkerPool) observeQueue(length int) {
+	w.peakQueueMutex.Lock()
+	if length > w.peakQueue {
+		w.peakQueue = length
+	}
+	w.peakQueueMutex.Unlock()
+}
+
 func (w *WorkerPool) createWorkers(count int) {
+	w.spawnBatchMutex.Lock()
+	batchSize := w.spawnBatch
+	w.spawnBatchMutex.Unlock()
+
+	ctx, queue := w.channels()
 	for i := 0; i < count; i++ {
-		go func() {
-			for {
-				select {
-				case job, ok := <-w.jobs:
-					if !ok {
-						return
-					}
-					w.incBusy()
-					w.run(job...)
-					w.decBusy()
-				case <-w.stop:
-					return
-				}
+		if batchSize > 0 && i > 0 && i%batchSize == 0 {
+			runtime.Gosched()
+		}
+		w.workers.Add(1)
+		go w.work(w.acquireID(), ctx, queue)
+	}
+}
+
+// Take the lowest worker id which is not held by a running worker
+func (w *WorkerPool) acquireID() int {
+	w.workerIDsMutex.Lock()
+	defer w.workerIDsMutex.Unlock()
+	for id, held := range w.workerIDs {
+		if !held {
+			w.workerIDs[id] = true
+			return id
+		}
+	}
+	w.workerIDs = append(w.workerIDs, true)
+	w.jobStarts = append(w.jobStarts, time.Time{})
+	return len(w.workerIDs) - 1
+}
+
+// Record when a worker starts a job, or the zero time when it finishes
+func (w *WorkerPool) markJob(id int, start time.Time) {
+	w.workerIDsMutex.Lock()
+	w.jobStarts[id] = start
+	w.workerIDsMutex.Unlock()
+}
+
+func (w *WorkerPool) releaseID(id int) {
+	w.workerIDsMutex.Lock()
+	w.workerIDs[id] = false
+	w.workerIDsMutex.Unlock()
+}
+
+// Run jobs until this worker is signalled to stop, the pool is stopped,
+// or the worker has been idle for longer than the idle timeout
+//
+// Takes the pool's context and job channel from the caller, so that it
+// never waits on closeMutex while a submission is blocked on it.
+func (w *WorkerPool) work(id int, ctx context.Context, queue chan job) {
+	defer w.workers.Done()
+	defer w.releaseID(id)
+	if w.onWorkerStart != nil {
+		w.onWorkerStart(id)
+	}
+	if w.onWorkerStop != nil {
+		defer w.onWorkerStop(id)
+	}
+	if w.logger != nil {
+		w.logger("worker_spawn", map[string]interface{}{"worker": id})
+		defer w.logger("worker_stop", map[string]interface{}{"worker": id})
+	}
+
+	var timer *time.Timer
+	var idle <-chan time.Time
+	if w.idleTimeout > 0 {
+		timer = time.NewTimer(w.idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	for {
+		stopping, stop := w.stopState()
+		if stopping {
+			return
+		}
+
+		paused, pause, resume := w.pauseState()
+		if paused {
+			// take no jobs until the pool is resumed
+			select {
+			case <-resume:
+			case <-stop:
+				// another worker may claim the batch first
+				continue
+			case <-ctx.Done():
+				return
 			}
-		}()
+			w.resetIdle(timer)
+			continue
+		}
+
+		select {
+		case <-pause:
+		case job, ok := <-queue:
+			if !ok {
+				return
+			}
+			if job.ctx.Err() != nil {
+				// the caller gave up before a worker was free
+				w.discard(job)
+				// wake Flush, which waits on busyChanged for
+				// jobs to leave the queue
+				w.busyMutex.Lock()
+				w.signalBusy()
+				w.busyMutex.Unlock()
+				break
+			}
+			// busy from here on, including while waiting for a turn,
+			// so that ScaleDownIdle does not count this worker as idle
+			w.incBusy()
+			if w.limiter != nil && !w.limiter.wait(ctx) {
+				// the pool was stopped while waiting for a turn
+				w.decBusy()
+				w.discard(job)
+				return
+			}
+			if !w.takeFuture(job) {
+				// the future already failed when the pool was stopped
+				w.decBusy()
+				w.finish(job)
+				break
+			}
+			w.runJob(id, job, ctx.Done())
+		case <-stop:
+			// another worker may claim the batch first
+		case <-ctx.Done():
+			return
+		case <-idle:
+			if w.retireIdle() {
+				return
+			}
+		}
+
+		w.resetIdle(timer)
+	}
+}
+
+// Restart a worker's idle timer, if it has one
+func (w *WorkerPool) resetIdle(timer *time.Timer) {
+	if timer == nil {
+		return
+	}
+	if !timer.Stop() {
+		// drain a timeout which fired while the worker was busy
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(w.idleTimeout)
+}
+
+func (w *WorkerPool) pauseState() (paused bool, pause, resume chan struct{}) {
+	w.pauseMutex.Lock()
+	defer w.pauseMutex.Unlock()
+	return w.paused, w.pause, w.resume
+}
+
+// Remove an idle worker from the pool's size, unless that would take
+// the pool below its minimum number of workers
+func (w *WorkerPool) retireIdle() bool {
+	w.sizeMutex.Lock()
+	oldSize := w.size
+	if oldSize <= w.minWorkers {
+		w.sizeMutex.Unlock()
+		return false
+	}
+	w.size--
+	w.sizeMutex.Unlock()
+
+	if w.logger != nil {
+		w.logger("scale_down", map[string]interface{}{"from": oldSize, "to": oldSize - 1})
 	}
+	w.emitScale(oldSize, oldSize-1)
+	return true
 }
 
-func (w *WorkerPool) modClose(change int) {
+// Run a job, giving up on sending its result or retrying it once done
+// is closed. The worker must already be counted as busy
+func (w *WorkerPool) runJob(workerID int, job job, done <-chan struct{}) {
+	ctx := job.ctx
+	if w.jobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.jobTimeout)
+		defer cancel()
+	}
+	var hookDone func(err error, recovered interface{})
+	if w.jobHook != nil {
+		ctx, hookDone = w.jobHook(ctx, job.args)
+	}
+
+	var result interface{}
+	var err error
+	if w.logger != nil {
+		w.logger("job_start", map[string]interface{}{"worker": workerID})
+	}
+	start := time.Now()
+	w.markJob(workerID, start)
+	if job.firstRun.IsZero() {
+		job.firstRun = start
+	}
+	if job.started != nil {
+		close(job.started)
+		// retries of the job must not close it again
+		job.started = nil
+	}
+	defer func() {
+		recovered := recover()
+		elapsed := time.Since(start)
+		w.markJob(workerID, time.Time{})
+		w.observeDuration(elapsed)
+		atomic.AddUint64(&w.processed, 1)
+		if recovered != nil {
+			atomic.AddUint64(&w.panicked, 1)
+		}
+		if w.jobTimeout > 0 && ctx.Err() == context.DeadlineExceeded && job.ctx.Err() == nil {
+			atomic.AddUint64(&w.timedOut, 1)
+		}
+		w.decBusy()
+		if hookDone != nil {
+			hookDone(err, recovered)
+		}
+		if w.logger != nil {
+			w.logJob(workerID, err, recovered)
+		}
+		if w.onComplete != nil {
+			w.onComplete(job.args, elapsed, recovered)
+		}
+		if err != nil && w.retry(job, err, done) {
+			return
+		}
+		if err != nil && w.deadLetter != nil {
+			w.deadLetter(job.args, err, job.attempts+1)
+		}
+		if job.future != nil {
+			job.future.complete(result, err, recovered)
+		}
+		w.finish(job)
+		if err != nil {
+			w.reportError(err)
+		}
+		if recovered != nil {
+			w.onPanicMutex.Lock()
+			onPanic := w.onPanic
+			w.onPanicMutex.Unlock()
+			if onPanic != nil {
+				onPanic(recovered, job.args)
+			}
+		}
+	}()
+	result, err = job.gen.handler()(ctx, workerID, job.args...)
+	if w.results != nil && job.future == nil {
+		select {
+		case w.results <- result:
+		case <-done:
+			// nobody will receive results from a stopped pool
+		}
+	}
+}
+
+func (w *WorkerPool) observeDuration(d time.Duration) {
+	atomic.AddUint64(&w.runTime, uint64(d))
+	if w.ewmaAlpha == 0 {
+		return
+	}
+	w.ewmaMutex.Lock()
+	if w.ewma == 0 {
+		// the first job starts the average
+		w.ewma = d
+	} else {
+		w.ewma += time.Duration(w.ewmaAlpha * float64(d-w.ewma))
+	}
+	w.ewmaMutex.Unlock()
+}
+
+func (w *WorkerPool) logJob(workerID int, err error, recovered interface{}) {
+	if recovered != nil {
+		w.logger("job_panic", map[string]interface{}{"worker": workerID, "panic": recovered})
+		return
+	}
+	fields := map[string]interface{}{"worker": workerID}
+	if err != nil {
+		fields["error"] = err
+	}
+	w.logger("job_done", fields)
+}
+
+// A request for a number of workers to stop after their current job
+type stopBatch struct {
+	count     int
+	remaining int
+
+	// closed once every worker in the batch has stopped
+	done chan struct{}
+}
+
+// Signal count workers to stop, returning how many stopped before the
+// WorkerPool was stopped (which stops every worker anyway)
+//
+// Blocks until the workers have finished their current jobs.
+func (w *WorkerPool) stopWorkers(count int) int {
+	if count == 0 {
+		return 0
+	}
+	return w.awaitStop(w.postStop(count))
+}
+
+// Wait for every worker in a batch to stop, returning how many stopped
+// before the WorkerPool was stopped
+func (w *WorkerPool) awaitStop(batch *stopBatch) int {
+	select {
+	case <-batch.done:
+		return batch.count
+	case <-w.Done():
+		return w.abandonStop(batch)
+	}
+}
+
+// Add a batch of count workers to stop, and cancel the context idle
+// workers are waiting on so they claim it
+func (w *WorkerPool) postStop(count int) *stopBatch {
+	batch := &stopBatch{count: count, remaining: count, done: make(chan struct{})}
+	w.closingMutex.Lock()
+	defer w.closingMutex.Unlock()
+	w.stopBatches = append(w.stopBatches, batch)
+	w.closing += count
+	w.stopCancel()
+	w.stopCtx, w.stopCancel = context.WithCancel(context.Background())
+	return batch
+}
+
+// Remove a batch which is no longer waited for, returning how many of
+// its workers had already stopped
+func (w *WorkerPool) abandonStop(batch *stopBatch) int {
+	w.closingMutex.Lock()
+	defer w.closingMutex.Unlock()
+	for i, b := range w.stopBatches {
+		if b == batch {
+			w.stopBatches = append(w.stopBatches[:i], w.stopBatches[i+1:]...)
+			w.closing -= batch.remaining
+			break
+		}
+	}
+	return batch.count - batch.remaining
+}
+
+// Claim a place in the oldest scale-down batch for the calling worker,
+// or get the channel which is closed when a new batch is added
+func (w *WorkerPool) stopState() (stopping bool, stop <-chan struct{}) {
 	w.closingMutex.Lock()
-	w.closing += change
+	defer w.closingMutex.Unlock()
+	if w.closing == 0 {
+		return false, w.stopCtx.Done()
+	}
+
+	batch := w.stopBatches[0]
+	batch.remaining--
+	w.closing--
+	if batch.remaining == 0 {
+		w.stopBatches = w.stopBatches[1:]
+		close(batch.done)
+	}
+	return true, nil
+}
+
+// Forget batches left over from before the WorkerPool was stopped,
+// whose workers have all exited
+func (w *WorkerPool) resetStops() {
+	w.closingMutex.Lock()
+	w.stopBatches = nil
+	w.closing = 0
 	w.closingMutex.Unlock()
 }
 
+func (w *WorkerPool) modDetached(change int) {
+	w.detachedMutex.Lock()
+	w.detached += change
+	w.detachedMutex.Unlock()
+}
+
 func (w *WorkerPool) incBusy() {
 	w.busyMutex.Lock()
 	w.busy++
+	if w.busy > w.peakBusy {
+		w.peakBusy = w.busy
+	}
+	w.signalBusy()
 	w.busyMutex.Unlock()
 }
 
 func (w *WorkerPool) decBusy() {
 	w.busyMutex.Lock()
 	w.busy--
+	w.signalBusy()
 	w.busyMutex.Unlock()
 }
+
+// must be called with busyMutex held
+func (w *WorkerPool) signalBusy() {
+	close(w.busyChanged)
+	w.busyChanged = make(chan struct{})
+}
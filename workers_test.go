@@ -1,7 +1,9 @@
 package workers
 
 import (
-	"math/rand"
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -19,6 +21,126 @@ func TestWorkerPool_Stop(t *testing.T) {
 	pool.Stop()
 }
 
+func TestWorkerPool_Closed(t *testing.T) {
+	pool := NewPool(1, func(...interface{}) {})
+	if pool.Closed() {
+		t.Error("pool should not be closed before it is stopped")
+	}
+	pool.Stop()
+	if !pool.Closed() {
+		t.Error("pool should be closed after it is stopped")
+	}
+	pool.Restart()
+	defer pool.Stop()
+	if pool.Closed() {
+		t.Error("pool should not be closed after it is restarted")
+	}
+}
+
+func TestWorkerPool_Done(t *testing.T) {
+	pool := NewPool(1, func(...interface{}) {})
+	done := pool.Done()
+	select {
+	case <-done:
+		t.Error("done channel should not be closed before the pool stops")
+	default:
+	}
+
+	go pool.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("done channel should be closed after the pool stops")
+	}
+}
+
+func TestWorkerPool_Stop_Twice(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+	pool.Stop()
+	pool.Stop() // must not panic
+
+	pool = NewPool(10, func(...interface{}) {})
+	pool.StopAndCount()
+	pool.Stop()
+}
+
+func TestWorkerPool_DrainAndStop(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(2, 10, func(...interface{}) {
+		<-time.After(time.Millisecond)
+		atomic.AddInt32(&ran, 1)
+	})
+	for i := 0; i < 10; i++ {
+		pool.Run(i)
+	}
+
+	pool.DrainAndStop()
+	if atomic.LoadInt32(&ran) != 10 {
+		t.Error("all 10 queued jobs should run, only", atomic.LoadInt32(&ran), "did")
+	}
+	select {
+	case <-pool.Context().Done():
+	default:
+		t.Error("context should be cancelled after the pool stops")
+	}
+}
+
+func TestWorkerPool_Shutdown(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(2, 10, func(...interface{}) {
+		atomic.AddInt32(&ran, 1)
+	})
+	for i := 0; i < 10; i++ {
+		pool.Run(i)
+	}
+	err := pool.Shutdown(context.Background())
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if atomic.LoadInt32(&ran) != 10 {
+		t.Error("all 10 queued jobs should run, only", atomic.LoadInt32(&ran), "did")
+	}
+
+	pool = NewPool(1, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	pool.Run(1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = pool.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+	if pool.Context().Err() == nil {
+		t.Error("context should be cancelled after the pool stops")
+	}
+}
+
+func TestWorkerPool_StopGraceful(t *testing.T) {
+	pool := NewPool(2, func(data ...interface{}) {
+		<-data[0].(chan bool)
+	})
+	quick, slow := make(chan bool), make(chan bool)
+	pool.Run(quick)
+	pool.Run(slow)
+	waitBusy(t, pool, 2)
+
+	go func() {
+		<-time.After(time.Millisecond)
+		close(quick)
+	}()
+	stragglers := pool.StopGraceful(20 * time.Millisecond)
+	if stragglers != 1 {
+		t.Error("stragglers should equal 1, not", stragglers)
+	}
+
+	close(slow)
+	pool = NewPool(2, func(...interface{}) {})
+	if stragglers = pool.StopGraceful(time.Second); stragglers != 0 {
+		t.Error("stragglers should equal 0, not", stragglers)
+	}
+}
+
 func TestWorkerPool_StopAndCount(t *testing.T) {
 	pool := NewPool(10, func(...interface{}) {})
 	pool.StopAndCount() // blocks until done
@@ -28,6 +150,718 @@ func TestWorkerPool_StopAndCount(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_Restart(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(2, 5, func(...interface{}) {
+		atomic.AddInt32(&ran, 1)
+	})
+	pool.Restart() // does nothing while running
+	pool.Stop()
+
+	pool.Restart()
+	defer pool.Stop()
+	for i := 0; i < 5; i++ {
+		pool.Run(i)
+	}
+	pool.Wait()
+	if atomic.LoadInt32(&ran) != 5 {
+		t.Error("all 5 jobs should run after restarting, only", atomic.LoadInt32(&ran), "did")
+	}
+	if pool.Context().Err() != nil {
+		t.Error("context should not be cancelled after restarting")
+	}
+	if pool.Size() != 2 {
+		t.Error("size should be 2, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_Restart_Concurrent(t *testing.T) {
+	pool := NewBufferedPool(2, 5, func(...interface{}) {})
+	defer pool.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			pool.Stop()
+			pool.Restart()
+		}
+	}()
+
+	// run with -race to check these readers against Restart
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		_ = pool.Context()
+		_ = pool.QueueLen()
+		_ = pool.QueueCap()
+		pool.Flush()
+	}
+}
+
+func TestWorkerPool_Run_Closed(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(1), WithBuffer(1), WithClosedPolicy(PolicyError))
+	err := pool.Run(1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	pool.Stop()
+	err = pool.Run(2)
+	if err != ErrClosed {
+		t.Error("Error should be", ErrClosed, "not", err)
+	}
+}
+
+func TestWorkerPool_MustRun(t *testing.T) {
+	ran := make(chan bool, 1)
+	pool := New(func(...interface{}) {
+		ran <- true
+	}, WithSize(1), WithClosedPolicy(PolicyError))
+	pool.MustRun(1)
+	<-ran
+	pool.Stop()
+
+	defer func() {
+		if recover() != ErrClosed {
+			t.Error("MustRun should panic with", ErrClosed, "whatever the closed policy")
+		}
+	}()
+	pool.MustRun(2)
+}
+
+func TestWorkerPool_RunSync(t *testing.T) {
+	gate := make(chan struct{})
+	pool := NewBufferedPool(1, 1, func(...interface{}) {
+		<-gate
+	})
+	defer pool.Stop()
+
+	err := pool.RunSync(1)
+	if err != nil {
+		t.Error("Error should be nil, not", err)
+	}
+	if pool.Busy() != 1 {
+		t.Error("the job should have started, but busy is", pool.Busy())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = pool.RunSync(2)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Error("RunSync should wait while the only worker is busy")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	gate <- struct{}{}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("RunSync should return once the job starts")
+	}
+	close(gate)
+}
+
+func TestWorkerPool_RunSync_Stop(t *testing.T) {
+	pool := NewBufferedPool(1, 1, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	pool.Run(1)
+
+	errs := make(chan error)
+	go func() {
+		errs <- pool.RunSync(2)
+	}()
+	<-time.After(10 * time.Millisecond) // wait for the job to be queued
+	pool.Stop()
+
+	if err := <-errs; err != ErrClosed {
+		t.Error("Error should be", ErrClosed, "not", err)
+	}
+}
+
+func TestWorkerPool_Run_Aliasing(t *testing.T) {
+	pool := NewBufferedPool(0, 1, func(...interface{}) {})
+
+	args := []interface{}{1}
+	pool.Run(args...)
+	args[0] = 2 // visible to the worker, since the slice is shared
+
+	job := pool.SnapshotQueue()[0]
+	if job[0] != 2 {
+		t.Error("Run should share the caller's slice, job has", job[0])
+	}
+}
+
+func TestWorkerPool_SafeRun(t *testing.T) {
+	pool := NewBufferedPool(0, 1, func(...interface{}) {})
+
+	args := []interface{}{1}
+	pool.SafeRun(args...)
+	args[0] = 2 // must not be visible to the worker
+
+	job := pool.SnapshotQueue()[0]
+	if job[0] != 1 {
+		t.Error("SafeRun should copy the caller's slice, job has", job[0])
+	}
+}
+
+func TestWorkerPool_OnPanic(t *testing.T) {
+	pool := NewPool(1, func(data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	})
+
+	recovered := make(chan interface{}, 1)
+	pool.OnPanic(func(r interface{}, job []interface{}) {
+		recovered <- r
+	})
+
+	pool.Run("panic")
+	if r := <-recovered; r != "bad job" {
+		t.Error("recovered value should be \"bad job\", not", r)
+	}
+
+	// the worker must survive the panic
+	err := pool.SubmitWithTimeout(time.Second, "ok")
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Busy() > 1 {
+		t.Error("busy workers should be at most 1, not", pool.Busy())
+	}
+}
+
+func TestWorkerPool_Wait(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(3, 20, func(...interface{}) {
+		<-time.After(time.Millisecond)
+		atomic.AddInt32(&ran, 1)
+	})
+	pool.Wait() // must not block on an idle pool
+
+	for i := 0; i < 20; i++ {
+		pool.Run(i)
+	}
+	pool.Wait()
+	if atomic.LoadInt32(&ran) != 20 {
+		t.Error("all 20 jobs should have run, not", atomic.LoadInt32(&ran))
+	}
+	if pool.Busy() != 0 {
+		t.Error("busy workers should equal 0, not", pool.Busy())
+	}
+}
+
+func TestWorkerPool_WaitIdle(t *testing.T) {
+	release := make(chan bool)
+	pool := NewBufferedPool(1, 5, func(...interface{}) {
+		<-release
+	})
+	defer pool.Stop()
+	pool.Run(1)
+	pool.Run(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err := pool.WaitIdle(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+
+	close(release)
+	err = pool.WaitIdle(context.Background())
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.QueueLen() != 0 {
+		t.Error("queue length should be 0, not", pool.QueueLen())
+	}
+}
+
+func TestWorkerPool_Flush(t *testing.T) {
+	release := make(chan bool)
+	pool := NewBufferedPool(3, 3, func(...interface{}) {
+		<-release
+	})
+	defer pool.Stop()
+	pool.Pause()
+	for i := 0; i < 3; i++ {
+		pool.Run(i)
+	}
+
+	flushed := make(chan bool)
+	go func() {
+		pool.Flush()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		t.Fatal("Flush should block while jobs are queued")
+	case <-time.After(time.Millisecond):
+	}
+
+	pool.Resume()
+	<-flushed
+	if pool.QueueLen() != 0 {
+		t.Error("queue length should be 0, not", pool.QueueLen())
+	}
+	close(release)
+}
+
+func TestWorkerPool_Processed(t *testing.T) {
+	pool := NewBufferedPool(2, 10, func(...interface{}) {})
+	for i := 0; i < 10; i++ {
+		pool.Run(i)
+	}
+	pool.Wait()
+
+	if pool.Processed() != 10 {
+		t.Error("processed jobs should equal 10, not", pool.Processed())
+	}
+}
+
+func TestWorkerPool_Panicked(t *testing.T) {
+	pool := NewBufferedPool(2, 10, func(data ...interface{}) {
+		if data[0].(int)%2 == 0 {
+			panic("bad job")
+		}
+	})
+	defer pool.Stop()
+	for i := 0; i < 5; i++ {
+		pool.Run(i)
+	}
+	pool.Wait()
+
+	if pool.Panicked() != 3 {
+		t.Error("panicked jobs should equal 3, not", pool.Panicked())
+	}
+	if pool.Processed() != 5 {
+		t.Error("processed jobs should equal 5, not", pool.Processed())
+	}
+}
+
+func TestWorkerPool_Pause(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(2, 10, func(...interface{}) {
+		atomic.AddInt32(&ran, 1)
+	})
+	pool.Pause()
+	if !pool.Paused() {
+		t.Error("pool should be paused")
+	}
+
+	for i := 0; i < 5; i++ {
+		pool.Run(i)
+	}
+	<-time.After(5 * time.Millisecond)
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Error("paused pool should not run jobs, but ran", atomic.LoadInt32(&ran))
+	}
+	if pool.QueueLen() != 5 {
+		t.Error("queue length should be 5, not", pool.QueueLen())
+	}
+
+	pool.Resume()
+	pool.Wait()
+	if atomic.LoadInt32(&ran) != 5 {
+		t.Error("resumed pool should run all 5 jobs, not", atomic.LoadInt32(&ran))
+	}
+}
+
+func TestWorkerPool_Pause_ScaleDown(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(4))
+	defer pool.Stop()
+	pool.Pause()
+	<-time.After(time.Millisecond) // wait for the workers to pause
+
+	done := make(chan error)
+	go func() {
+		done <- pool.ScaleDown(2)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Error should be nil, not", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ScaleDown should not block on paused workers")
+	}
+	<-time.After(time.Millisecond) // wait for the stopped workers to exit
+	if pool.Excess() != 0 {
+		t.Error("pool excess should be 0, not", pool.Excess())
+	}
+	if workers := len(pool.WorkerStates()); workers != 2 {
+		t.Error("running workers should equal 2, not", workers)
+	}
+}
+
+func TestWorkerPool_Pause_Detach(t *testing.T) {
+	pool := New(func(...interface{}) {}, WithSize(2))
+	defer pool.Stop()
+	pool.Pause()
+	<-time.After(time.Millisecond) // wait for the workers to pause
+
+	ran := make(chan struct{})
+	go pool.Detach(func() {
+		close(ran)
+	})
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Detach should take a paused worker")
+	}
+	<-time.After(time.Millisecond) // wait for the replacement worker
+	if workers := len(pool.WorkerStates()); workers != 2 {
+		t.Error("running workers should equal 2, not", workers)
+	}
+}
+
+func TestWorkerPool_Context(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+
+	select {
+	case <-pool.Context().Done():
+		t.Error("context should not be cancelled before the pool stops")
+	default:
+	}
+
+	pool.Stop()
+	select {
+	case <-pool.Context().Done():
+	default:
+		t.Error("context should be cancelled after the pool stops")
+	}
+}
+
+func TestWorkerPool_Detach(t *testing.T) {
+	pool := NewPool(2, func(...interface{}) {})
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	pool.Detach(func() {
+		<-release
+		close(done)
+	})
+	if pool.Detached() != 1 {
+		t.Error("detached workers should equal 1, not", pool.Detached())
+	}
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+
+	// the remaining worker keeps running jobs
+	pool.Run(struct{}{})
+
+	close(release)
+	<-done
+	<-time.After(time.Millisecond) // wait for the replacement worker
+	if pool.Detached() != 0 {
+		t.Error("detached workers should equal 0, not", pool.Detached())
+	}
+}
+
+func TestWorkerPool_Detach_Restart(t *testing.T) {
+	pool := NewPool(2, func(...interface{}) {})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	pool.Detach(func() {
+		<-release
+		close(done)
+	})
+
+	pool.Stop()
+	pool.Restart()
+	defer pool.Stop()
+	close(release)
+	<-done
+	<-time.After(time.Millisecond) // wait for the replacement worker
+
+	if workers := len(pool.WorkerStates()); workers != 2 {
+		t.Error("running workers should equal 2, not", workers)
+	}
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_TrySubmit(t *testing.T) {
+	pool := NewBufferedPool(0, 1, func(...interface{}) {})
+	if !pool.TrySubmit(1) {
+		t.Error("job should be accepted while the buffer has space")
+	}
+	if pool.TrySubmit(2) {
+		t.Error("job should be rejected while the buffer is full")
+	}
+
+	unbuffered := NewPool(1, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	unbuffered.Run(1)
+	if unbuffered.TrySubmit(2) {
+		t.Error("job should be rejected while no worker is ready")
+	}
+}
+
+func TestWorkerPool_SubmitWithTimeout(t *testing.T) {
+	pool := NewBufferedPool(0, 1, func(...interface{}) {})
+
+	err := pool.SubmitWithTimeout(time.Millisecond, 1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	err = pool.SubmitWithTimeout(time.Millisecond, 2)
+	if err != ErrSubmitTimeout {
+		t.Error("Error should be", ErrSubmitTimeout, "not", err)
+	}
+}
+
+func TestWorkerPool_RunAfter(t *testing.T) {
+	ran := make(chan time.Time, 2)
+	pool := NewPool(1, func(...interface{}) {
+		ran <- time.Now()
+	})
+
+	start := time.Now()
+	pool.RunAfter(5*time.Millisecond, 1)
+	pool.RunAt(start.Add(5*time.Millisecond), 2)
+	for i := 0; i < 2; i++ {
+		if at := <-ran; at.Sub(start) < 5*time.Millisecond {
+			t.Error("job should run after the delay, not after", at.Sub(start))
+		}
+	}
+
+	pool.RunAfter(time.Millisecond, 3)
+	pool.Stop() // must cancel the pending timer without panicking
+	<-time.After(5 * time.Millisecond)
+	if len(ran) != 0 {
+		t.Error("delayed job should not run after the pool stops")
+	}
+}
+
+func TestWorkerPool_RunWaitCtx(t *testing.T) {
+	ran := make(chan bool, 1)
+	pool := NewBufferedPool(0, 1, func(...interface{}) {
+		ran <- true
+	})
+	defer pool.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := pool.RunWaitCtx(ctx, 1)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+
+	go cancel()
+	err = pool.RunWaitCtx(ctx, 2) // blocks on the full buffer
+	if err != context.Canceled {
+		t.Error("Error should be", context.Canceled, "not", err)
+	}
+
+	// the accepted job still runs, even though its context was cancelled
+	_ = pool.ScaleUp(1)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Error("accepted job should run after the context is cancelled")
+	}
+}
+
+func TestWorkerPool_RunBatchCtx(t *testing.T) {
+	pool := NewBufferedPool(0, 3, func(...interface{}) {})
+	jobs := [][]interface{}{{1}, {2}, {3}, {4}, {5}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	n, err := pool.RunBatchCtx(ctx, jobs)
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+	if n != 3 {
+		t.Error("submitted jobs should equal 3, not", n)
+	}
+
+	pool.SnapshotQueue()
+	n, err = pool.RunBatchCtx(context.Background(), jobs[:2])
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if n != 2 {
+		t.Error("submitted jobs should equal 2, not", n)
+	}
+}
+
+func TestWorkerPool_RunBatchCtx_Cancel(t *testing.T) {
+	var ran int32
+	gate := make(chan struct{})
+	pool := NewBufferedPool(1, 3, func(...interface{}) {
+		<-gate
+		atomic.AddInt32(&ran, 1)
+	})
+	defer pool.Stop()
+	pool.Run(0)
+	waitBusy(t, pool, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n, err := pool.RunBatchCtx(ctx, [][]interface{}{{1}, {2}, {3}})
+	if n != 3 || err != nil {
+		t.Error("all 3 jobs should be submitted, not", n, err)
+	}
+	cancel()
+	close(gate)
+	pool.Wait()
+
+	// jobs counted as submitted run even though the context was cancelled
+	if atomic.LoadInt32(&ran) != 4 {
+		t.Error("all 4 jobs should run, only", atomic.LoadInt32(&ran), "did")
+	}
+}
+
+func TestWorkerPool_SubmitBatch(t *testing.T) {
+	pool := NewBufferedPool(0, 5, func(...interface{}) {})
+	pool.SubmitBatch([][]interface{}{{1}, {2}, {3}})
+	if pool.QueueLen() != 3 {
+		t.Error("queue length should be 3, not", pool.QueueLen())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	n, err := pool.SubmitBatchCtx(ctx, [][]interface{}{{4}, {5}, {6}})
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+	if n != 2 {
+		t.Error("submitted jobs should equal 2, not", n)
+	}
+}
+
+func TestWorkerPool_SnapshotQueue(t *testing.T) {
+	pool := NewBufferedPool(0, 5, func(...interface{}) {})
+	pool.Run(1)
+	pool.Run(2, "two")
+
+	jobs := pool.SnapshotQueue()
+	if len(jobs) != 2 {
+		t.Fatal("snapshot should contain 2 jobs, not", len(jobs))
+	}
+	if jobs[1][0] != 2 || jobs[1][1] != "two" {
+		t.Error("snapshot should preserve job order and arguments, not", jobs)
+	}
+	if len(pool.SnapshotQueue()) != 0 {
+		t.Error("queue should be empty after a snapshot")
+	}
+
+	restored := NewBufferedPool(0, 5, func(...interface{}) {})
+	restored.LoadQueue(jobs)
+	if len(restored.SnapshotQueue()) != 2 {
+		t.Error("restored pool should contain 2 jobs")
+	}
+}
+
+func TestWorkerPool_SnapshotQueue_Stop(t *testing.T) {
+	pool := NewBufferedPool(0, 5, func(...interface{}) {})
+	pool.Run(1)
+	pool.Run(2)
+	pool.Stop()
+
+	jobs := pool.SnapshotQueue()
+	if len(jobs) != 2 {
+		t.Error("snapshot should contain the 2 jobs left by Stop, not", len(jobs))
+	}
+	if len(pool.SnapshotQueue()) != 0 {
+		t.Error("queue should be empty after a snapshot")
+	}
+}
+
+func TestWorkerPool_RecycleWorkers(t *testing.T) {
+	var ran int32
+	pool := NewBufferedPool(2, 10, func(...interface{}) {
+		<-time.After(time.Millisecond)
+		atomic.AddInt32(&ran, 1)
+	})
+
+	for i := 0; i < 10; i++ {
+		pool.Run(i)
+	}
+	pool.RecycleWorkers()
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt32(&ran) != 10 {
+		select {
+		case <-deadline:
+			t.Fatal("all 10 queued jobs should run, only", atomic.LoadInt32(&ran), "did")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestWorkerPool_QueueLen(t *testing.T) {
+	pool := NewBufferedPool(0, 5, func(...interface{}) {})
+	pool.Run(1)
+	pool.Run(2)
+
+	if pool.QueueLen() != 2 {
+		t.Error("queue length should be 2, not", pool.QueueLen())
+	}
+	if pool.QueueCap() != 5 {
+		t.Error("queue capacity should be 5, not", pool.QueueCap())
+	}
+}
+
+func TestWorkerPool_PeakQueueLength(t *testing.T) {
+	pool := NewBufferedPool(0, 5, func(...interface{}) {})
+	for i := 0; i < 3; i++ {
+		pool.Run(i)
+	}
+	pool.SnapshotQueue()
+	pool.Run(3)
+
+	if pool.PeakQueueLength() != 3 {
+		t.Error("peak queue length should be 3, not", pool.PeakQueueLength())
+	}
+
+	pool.ResetPeakQueue()
+	if pool.PeakQueueLength() != 1 {
+		t.Error("peak queue length should be 1 after reset, not", pool.PeakQueueLength())
+	}
+}
+
+func TestWorkerPool_ScaleDown_Stop(t *testing.T) {
+	pool := NewPool(5, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	for i := 0; i < 5; i++ {
+		pool.Run(struct{}{})
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- pool.ScaleDown(0) // blocks on the busy workers
+	}()
+	<-time.After(time.Millisecond)
+	pool.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Error should be nil, not", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Error("Stop should abort a blocked ScaleDown")
+	}
+	if pool.Excess() != 0 {
+		t.Error("closing count should be 0, not", pool.Excess())
+	}
+}
+
 func TestWorkerPool_ScaleUp(t *testing.T) {
 	pool := NewPool(10, func(...interface{}) {})
 
@@ -45,6 +879,20 @@ func TestWorkerPool_ScaleUp(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_SetSpawnBatchSize(t *testing.T) {
+	pool := NewPool(0, func(...interface{}) {})
+	pool.SetSpawnBatchSize(100)
+
+	err := pool.ScaleUp(1000)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 1000 {
+		t.Error("pool size should be 1000, not", pool.Size())
+	}
+	pool.StopAndCount()
+}
+
 func TestWorkerPool_ScaleDown(t *testing.T) {
 	pool := NewPool(10, func(...interface{}) {})
 
@@ -62,6 +910,93 @@ func TestWorkerPool_ScaleDown(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_ScaleDown_Busy(t *testing.T) {
+	gate := make(chan struct{})
+	pool := NewPool(3, func(...interface{}) {
+		<-gate
+	})
+	pool.Run(struct{}{})
+	pool.Run(struct{}{})
+	pool.Run(struct{}{})
+	<-time.After(time.Millisecond) // wait for every worker to take a job
+
+	done := make(chan struct{})
+	go func() {
+		_ = pool.ScaleDown(1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Error("ScaleDown should wait for busy workers to finish their jobs")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(gate)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ScaleDown should return once the workers have stopped")
+	}
+	if pool.Excess() != 0 {
+		t.Error("pool excess should be 0, not", pool.Excess())
+	}
+	if pool.Size() != 1 {
+		t.Error("pool size should be 1, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_ScaleDownIdle(t *testing.T) {
+	pool := NewPool(4, func(...interface{}) {
+		<-make(chan bool) // block forever (until test ends)
+	})
+	pool.Run(struct{}{})
+	pool.Run(struct{}{})
+	<-time.After(time.Millisecond) // wait for idle workers to start waiting
+
+	_, err := pool.ScaleDownIdle(5)
+	if err == nil {
+		t.Error("pool must not accept a higher value when scaling down")
+	}
+
+	size, err := pool.ScaleDownIdle(0)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if size != 2 {
+		t.Error("pool size should be 2 with 2 busy workers, not", size)
+	}
+	if pool.Size() != 2 {
+		t.Error("pool size should be 2, not", pool.Size())
+	}
+}
+
+func TestWorkerPool_ScaleDownIdle_RateLimit(t *testing.T) {
+	gate := make(chan struct{})
+	pool := New(func(...interface{}) {
+		<-gate
+	}, WithSize(2), WithRateLimit(2, 1))
+	defer close(gate)
+	defer pool.Stop()
+	pool.Run(1)
+	pool.Run(2) // waits for its turn for half a second
+	<-time.After(time.Millisecond)
+
+	done := make(chan int)
+	go func() {
+		size, _ := pool.ScaleDownIdle(0)
+		done <- size
+	}()
+	select {
+	case size := <-done:
+		if size != 2 {
+			t.Error("pool size should be 2 with a worker waiting for its turn, not", size)
+		}
+	case <-time.After(time.Second):
+		t.Error("ScaleDownIdle should not wait for a worker holding a job")
+	}
+}
+
 func TestWorkerPool_ScaleTo(t *testing.T) {
 	pool := NewPool(10, func(...interface{}) {})
 
@@ -87,6 +1022,56 @@ func TestWorkerPool_ScaleTo(t *testing.T) {
 	}
 }
 
+func TestWorkerPool_Resize(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+	defer pool.Stop()
+
+	err := pool.Resize(10)
+	if err != nil {
+		t.Error("resizing to the same size should succeed, not", err.Error())
+	}
+	err = pool.Resize(5)
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
+	if pool.Size() != 5 {
+		t.Error("pool size should be 5, not", pool.Size())
+	}
+	err = pool.Resize(-1)
+	if err == nil {
+		t.Error("pool must not accept a negative size")
+	}
+}
+
+func TestWorkerPool_ScaleBy(t *testing.T) {
+	pool := NewPool(10, func(...interface{}) {})
+	defer pool.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = pool.ScaleUpBy(3)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = pool.ScaleDownBy(1)
+		}()
+	}
+	wg.Wait()
+	if pool.Size() != 20 {
+		t.Error("pool size should be 20, not", pool.Size())
+	}
+
+	if err := pool.ScaleDownBy(21); err == nil {
+		t.Error("pool must not scale down below zero")
+	}
+	if err := pool.ScaleUpBy(0); err == nil {
+		t.Error("pool must not accept a delta of zero")
+	}
+}
+
 func TestWorkerPool_Busy(t *testing.T) {
 	pool := NewPool(10, func(...interface{}) {
 		<-make(chan bool) // block forever (until test ends)
@@ -95,7 +1080,7 @@ func TestWorkerPool_Busy(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		go pool.Run(struct{}{})
 	}
-	<-time.After(10 * time.Microsecond) // wait for goroutines to start jobs
+	waitBusy(t, pool, 5) // wait for goroutines to start jobs
 	if pool.Busy() != 5 {
 		t.Error("busy workers should equal 5, not", pool.Busy())
 	}
@@ -104,7 +1089,7 @@ func TestWorkerPool_Busy(t *testing.T) {
 		// 5 running, 10 additional | max 10
 		go pool.Run(struct{}{})
 	}
-	<-time.After(10 * time.Microsecond) // wait for goroutines to start jobs
+	waitBusy(t, pool, 10) // wait for goroutines to start jobs
 	if pool.Busy() != 10 {
 		t.Error("busy workers should equal 10, not", pool.Busy())
 	}
@@ -118,7 +1103,7 @@ func TestWorkerPool_Waiting(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		go pool.Run(struct{}{})
 	}
-	<-time.After(10 * time.Microsecond) // wait for goroutines to start jobs
+	waitBusy(t, pool, 5) // wait for goroutines to start jobs
 	if pool.Waiting() != 5 {
 		t.Error("waiting workers should equal 5, not", pool.Waiting())
 	}
@@ -127,38 +1112,157 @@ func TestWorkerPool_Waiting(t *testing.T) {
 		// 5 running, 10 additional | max 10
 		go pool.Run(struct{}{})
 	}
-	<-time.After(10 * time.Microsecond) // wait for goroutines to start jobs
+	waitBusy(t, pool, 10) // wait for goroutines to start jobs
 	if pool.Waiting() != 0 {
 		t.Error("busy workers should equal 0, not", pool.Waiting())
 	}
 }
 
-func TestWorkerPool_ScaleRandom(t *testing.T) {
-	rand.Seed(time.Now().UnixNano())
-	pool := NewPool(10, func(...interface{}) {
+func TestWorkerPool_PeakBusy(t *testing.T) {
+	release := make(chan struct{})
+	pool := NewPool(5, func(...interface{}) {
+		<-release
+	})
+	for i := 0; i < 3; i++ {
+		go pool.Run(struct{}{})
+	}
+	waitBusy(t, pool, 3)
+	close(release)
+	pool.Wait()
+
+	if pool.PeakBusy() != 3 {
+		t.Error("peak busy workers should equal 3, not", pool.PeakBusy())
+	}
+	pool.ResetPeak()
+	if pool.PeakBusy() != 0 {
+		t.Error("peak busy workers should equal 0 after reset, not", pool.PeakBusy())
+	}
+}
+
+func TestWorkerPool_Waiting_Concurrent(t *testing.T) {
+	pool := NewBufferedPool(10, 100, func(...interface{}) {
+		<-time.After(100 * time.Microsecond)
+	})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				_ = pool.ScaleTo(1 + i%20)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				pool.TrySubmit(struct{}{})
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if waiting := pool.Waiting(); waiting < 0 || waiting > 20 {
+			t.Error("waiting workers should be between 0 and 20, not", waiting)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	pool.Stop()
+}
+
+func TestWorkerPool_WaitBusy(t *testing.T) {
+	pool := NewPool(2, func(...interface{}) {
 		<-make(chan bool) // block forever (until test ends)
 	})
+	go pool.Run(struct{}{})
+
+	err := pool.WaitBusy(1, context.Background())
+	if err != nil {
+		t.Error("Error should be nil, not", err.Error())
+	}
 
-	// scaling random in goroutines has the
-	// potential to cause scaling issues
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	err = pool.WaitBusy(2, ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Error should be", context.DeadlineExceeded, "not", err)
+	}
+}
 
-	// this is mostly used to ensure that upsizing
-	// *during* a downsize doesn't cause problems
-	// assuming you always upsize AFTER a downsize
-	// (goroutines here prevent that order)
+func waitBusy(t *testing.T, pool *WorkerPool, n int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.WaitBusy(n, ctx); err != nil {
+		t.Fatal("busy workers never reached", n)
+	}
+}
 
-	go pool.ScaleTo(100)
-	go pool.ScaleTo(25)
-	go pool.ScaleTo(80)
-	go pool.ScaleTo(125)
-	go pool.ScaleTo(60)
+func TestWorkerPool_Scale_Concurrent(t *testing.T) {
+	var live int32
+	pool := New(func(...interface{}) {},
+		WithSize(10),
+		WithWorkerStart(func(int) {
+			atomic.AddInt32(&live, 1)
+		}),
+		WithWorkerStop(func(int) {
+			atomic.AddInt32(&live, -1)
+		}),
+	)
+	defer pool.Stop()
 
-	// run this one last
-	<-time.After(time.Microsecond)
-	pool.ScaleTo(50)
+	// every call works from the size left by the one before it, so
+	// however they interleave, the workers match the final size
+	var wg sync.WaitGroup
+	for _, size := range []int{100, 25, 80, 125, 60, 5, 40} {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+			_ = pool.ScaleTo(size)
+		}(size)
+	}
+	wg.Wait()
+	_ = pool.ScaleTo(50)
 
-	<-time.After(1 * time.Millisecond)
 	if pool.Size() != 50 {
 		t.Error("pool size should be 50, not", pool.Size())
 	}
+	if pool.Excess() != 0 {
+		t.Error("closing count should be 0, not", pool.Excess())
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&live) != 50 && time.Now().Before(deadline) {
+		<-time.After(time.Millisecond)
+	}
+	if atomic.LoadInt32(&live) != 50 {
+		t.Error("running workers should equal 50, not", atomic.LoadInt32(&live))
+	}
+}
+
+func TestWorkerPool_AvgDuration(t *testing.T) {
+	pool := NewPool(1, func(data ...interface{}) {
+		<-time.After(data[0].(time.Duration))
+	})
+	defer pool.Stop()
+	if pool.AvgDuration() != 0 {
+		t.Error("average duration should be 0 before any jobs, not", pool.AvgDuration())
+	}
+
+	pool.Run(2 * time.Millisecond)
+	pool.Run(4 * time.Millisecond)
+	pool.Wait()
+	if avg := pool.AvgDuration(); avg < 3*time.Millisecond || avg > 50*time.Millisecond {
+		t.Error("average duration should be about 3ms, not", avg)
+	}
 }
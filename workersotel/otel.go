@@ -0,0 +1,39 @@
+// Package workersotel traces WorkerPool jobs with OpenTelemetry
+//
+// It is a separate module so that the core workers package does not
+// depend on OpenTelemetry.
+package workersotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Zytekaron/go-workers"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the span started for each job
+const SpanName = "worker.job"
+
+// Trace every job with a span started from tracer, as a child of the
+// span in the context the job was submitted with
+//
+// The span ends when the run function returns. Errors returned by the
+// run function, and panics, are recorded on the span. Pass the context
+// to the run function (see workers.NewCtx) to create child spans.
+func WithTracer(tracer trace.Tracer) workers.Option {
+	return workers.WithJobHook(func(ctx context.Context, data []interface{}) (context.Context, func(error, interface{})) {
+		ctx, span := tracer.Start(ctx, SpanName)
+		return ctx, func(err error, recovered interface{}) {
+			if recovered != nil {
+				err = fmt.Errorf("panic: %v", recovered)
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	})
+}
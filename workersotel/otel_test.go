@@ -0,0 +1,50 @@
+package workersotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Zytekaron/go-workers"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	pool := workers.NewCtx(func(ctx context.Context, data ...interface{}) {
+		if data[0] == "panic" {
+			panic("bad job")
+		}
+	}, workers.WithSize(1), WithTracer(tracer))
+	defer pool.Stop()
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_ = pool.RunCtx(ctx, "ok")
+	_ = pool.RunCtx(ctx, "panic")
+	pool.Wait()
+	parent.End()
+
+	var jobs []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == SpanName {
+			jobs = append(jobs, span)
+		}
+	}
+	if len(jobs) != 2 {
+		t.Fatal("job spans should equal 2, not", len(jobs))
+	}
+	for _, span := range jobs {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Error("job span should be a child of the submitting span")
+		}
+	}
+	if jobs[0].Status().Code == codes.Error {
+		t.Error("successful job span should not have an error status")
+	}
+	if jobs[1].Status().Code != codes.Error {
+		t.Error("panicking job span should have an error status, not", jobs[1].Status().Code)
+	}
+}
@@ -0,0 +1,66 @@
+// Package workersprom exports WorkerPool metrics to Prometheus
+//
+// It is a separate module so that the core workers package does not
+// depend on the Prometheus client.
+package workersprom
+
+import (
+	"github.com/Zytekaron/go-workers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Register a collector which publishes the pool's metrics, labelled
+// with pool=name, each time the registerer is scraped
+func Register(pool *workers.WorkerPool, registerer prometheus.Registerer, name string) error {
+	return registerer.Register(NewCollector(pool, name))
+}
+
+// A prometheus.Collector reading a WorkerPool's Stats snapshot
+type Collector struct {
+	pool *workers.WorkerPool
+
+	size      *prometheus.Desc
+	busy      *prometheus.Desc
+	waiting   *prometheus.Desc
+	queueLen  *prometheus.Desc
+	processed *prometheus.Desc
+	panicked  *prometheus.Desc
+}
+
+// Create a new Collector for a pool, labelled with pool=name
+func NewCollector(pool *workers.WorkerPool, name string) *Collector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("workers", "pool", metric), help, nil, labels)
+	}
+	return &Collector{
+		pool:      pool,
+		size:      desc("size", "The total number of workers."),
+		busy:      desc("busy", "The number of busy workers."),
+		waiting:   desc("waiting", "The number of workers waiting for jobs."),
+		queueLen:  desc("queue_length", "The number of jobs waiting in the job buffer."),
+		processed: desc("processed_total", "The number of jobs which have finished running."),
+		panicked:  desc("panicked_total", "The number of jobs which panicked."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.busy
+	ch <- c.waiting
+	ch <- c.queueLen
+	ch <- c.processed
+	ch <- c.panicked
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.Stats()
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.busy, prometheus.GaugeValue, float64(stats.Busy))
+	ch <- prometheus.MustNewConstMetric(c.waiting, prometheus.GaugeValue, float64(stats.Waiting))
+	ch <- prometheus.MustNewConstMetric(c.queueLen, prometheus.GaugeValue, float64(stats.QueueLen))
+	ch <- prometheus.MustNewConstMetric(c.processed, prometheus.CounterValue, float64(stats.Processed))
+	ch <- prometheus.MustNewConstMetric(c.panicked, prometheus.CounterValue, float64(stats.Panicked))
+}
@@ -0,0 +1,37 @@
+package workersprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zytekaron/go-workers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegister(t *testing.T) {
+	pool := workers.NewBufferedPool(3, 5, func(...interface{}) {})
+	defer pool.Stop()
+	pool.Run(1)
+	pool.Wait()
+
+	registry := prometheus.NewRegistry()
+	err := Register(pool, registry, "test")
+	if err != nil {
+		t.Fatal("Error should be nil, not", err.Error())
+	}
+
+	expected := `
+# HELP workers_pool_processed_total The number of jobs which have finished running.
+# TYPE workers_pool_processed_total counter
+workers_pool_processed_total{pool="test"} 1
+# HELP workers_pool_size The total number of workers.
+# TYPE workers_pool_size gauge
+workers_pool_size{pool="test"} 3
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"workers_pool_processed_total", "workers_pool_size")
+	if err != nil {
+		t.Error(err)
+	}
+}